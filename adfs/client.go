@@ -0,0 +1,42 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package adfs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/allcloud-io/clisso/htmlform"
+)
+
+// Client represents an ADFS login client.
+type Client struct {
+	*htmlform.Client
+}
+
+// InitSSO starts an IdP-initiated sign-on to the given relying party and returns the first page
+// of the login flow.
+func (c *Client) InitSSO(relyingParty string) (*htmlform.Page, error) {
+	u := fmt.Sprintf("%s/adfs/ls/IdpInitiatedSignOn.aspx?%s", c.BaseURL,
+		url.Values{"loginToRp": {relyingParty}}.Encode())
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	return c.Load(req)
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL string) (*Client, error) {
+	c, err := htmlform.NewClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{Client: c}, nil
+}
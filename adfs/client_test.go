@@ -0,0 +1,157 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package adfs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+const loginPage = `<html><body>
+<form method="post" id="loginForm" autocomplete="off" novalidate="novalidate" onKeyPress="if (event &amp;&amp; event.keyCode == 13) Login.submitLoginRequest();" action="/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=urn:amazon:webservices&amp;client-request-id=123">
+	%s
+	<input id="userNameInput" name="UserName" type="email" value="" tabindex="1" placeholder="someone@example.com" autocomplete="off">
+	<input id="passwordInput" name="Password" type="password" tabindex="2" placeholder="Password" autocomplete="off">
+	<input id="optionForms" type="hidden" name="AuthMethod" value="FormsAuthentication"/>
+	<span id="submitButton" class="submit" tabindex="4" role="button" onKeyPress="if (event &amp;&amp; event.keyCode == 32) Login.submitLoginRequest();" onclick="return Login.submitLoginRequest();">Sign in</span>
+</form></body></html>`
+
+const optionsPage = `<html><body>
+<form id="options" method="post" action="/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=urn:amazon:webservices&amp;client-request-id=123">
+	<input id="optionSelection" type="hidden" name="AuthMethod">
+	<input type="hidden" name="Context" value="ctx">
+</form>
+<div id="authOptions">
+	<a href="#" id="WindowsAzureMultiFactorAuthentication" onclick="return AuthOptions.selectAuthMethod('WindowsAzureMultiFactorAuthentication');">Azure MFA</a>
+	<a href="#" id="AzureMfaAuthentication" onclick="return AuthOptions.selectAuthMethod('AzureMfaAuthentication');">Use a verification code</a>
+</div></body></html>`
+
+const mfaPage = `<html><body>
+<form method="post" id="loginForm" autocomplete="off" action="/adfs/ls/IdpInitiatedSignOn.aspx?loginToRp=urn:amazon:webservices&amp;client-request-id=123">
+	%s
+	<input id="authMethod" type="hidden" name="AuthMethod" value="AzureMfaAuthentication"/>
+	<input id="context" type="hidden" name="Context" value="ctx"/>
+	<input id="verificationCodeInput" name="VerificationCode" type="text" value="" autocomplete="off">
+	<input id="signInButton" type="submit" class="submit" value="Sign in">
+</form></body></html>`
+
+const samlPage = `<html><body><form method="POST" name="hiddenform" action="https://signin.aws.amazon.com:443/saml">
+<input type="hidden" name="SAMLResponse" value="fake_assertion" />
+<noscript><p>Script is disabled. Click Submit to continue.</p><input type="submit" value="Submit" /></noscript>
+</form></body></html>`
+
+func getTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/adfs/ls/IdpInitiatedSignOn.aspx", r.URL.Path)
+		assert.Equal(t, "urn:amazon:webservices", r.URL.Query().Get("loginToRp"))
+
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, loginPage, "")
+			return
+		}
+
+		switch r.PostFormValue("AuthMethod") {
+		case "FormsAuthentication":
+			if r.PostFormValue("UserName") != `CORP\user` || r.PostFormValue("Password") != "secret" {
+				fmt.Fprintf(w, loginPage, `<span id="errorText" for="">Incorrect user ID or password.</span>`)
+				return
+			}
+			fmt.Fprint(w, optionsPage)
+		case "AzureMfaAuthentication":
+			assert.Equal(t, "ctx", r.PostFormValue("Context"))
+			switch r.PostFormValue("VerificationCode") {
+			case "":
+				fmt.Fprintf(w, mfaPage, "")
+			case "123456":
+				fmt.Fprint(w, samlPage)
+			default:
+				fmt.Fprintf(w, mfaPage, `<div id="error"><span id="errorText">The code is invalid.</span></div>`)
+			}
+		default:
+			t.Errorf("unexpected auth method %q", r.PostFormValue("AuthMethod"))
+		}
+	}))
+}
+
+func TestLogin(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	testutil.WithStdin(t, "123456\n", func() {
+		stdout, stderr := testutil.CaptureOutput(t, func() {
+			assertion, err := login(c, &loginParams{
+				RelyingParty: DefaultRelyingParty,
+				Username:     `CORP\user`,
+				Password:     "secret",
+				MFAMethod:    "AzureMfaAuthentication",
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, "fake_assertion", assertion)
+		})
+		// The OTP prompt goes to stderr when not interactive, since stdout may carry credentials.
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "Please enter the OTP")
+	})
+}
+
+func TestLoginWrongOTP(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	testutil.WithStdin(t, "000000\n", func() {
+		_, err := login(c, &loginParams{
+			RelyingParty: DefaultRelyingParty,
+			Username:     `CORP\user`,
+			Password:     "secret",
+			MFAMethod:    "AzureMfaAuthentication",
+		})
+		assert.EqualError(t, err, "MFA verification failed: The code is invalid.")
+	})
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{
+		RelyingParty: DefaultRelyingParty,
+		Username:     `CORP\user`,
+		Password:     "wrong",
+	})
+	assert.EqualError(t, err, "login failed: Incorrect user ID or password.")
+}
+
+func TestGetAuthMethod(t *testing.T) {
+	methods := []string{"WindowsAzureMultiFactorAuthentication", "AzureMfaAuthentication"}
+
+	m, err := getAuthMethod(methods, "AzureMfaAuthentication")
+	assert.Nil(t, err)
+	assert.Equal(t, "AzureMfaAuthentication", m)
+
+	m, err = getAuthMethod(methods, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "WindowsAzureMultiFactorAuthentication", m)
+
+	_, err = getAuthMethod(nil, "")
+	assert.EqualError(t, err, "no MFA method offered by ADFS")
+}
@@ -0,0 +1,257 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package adfs
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/htmlform"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/icza/gog"
+)
+
+const (
+	// DefaultRelyingParty is the identifier of the AWS relying party trust.
+	DefaultRelyingParty = "urn:amazon:webservices"
+
+	// Selectors of the forms making up the ADFS login flow. ADFS uses the loginForm ID for both
+	// the forms based login and the pages of most MFA adapters.
+	loginFormSelector   = "form#loginForm"
+	optionsFormSelector = "form#options"
+
+	// otpInputSelector matches the input an MFA adapter asks the one-time password for.
+	otpInputSelector = `input[type="text"], input[type="tel"], input[type="number"], input[type="password"], input:not([type])`
+
+	// maxPages limits the number of pages followed during a single login.
+	maxPages = 10
+)
+
+var (
+	keyChain = keychain.DefaultKeychain{}
+
+	authMethodRegex = regexp.MustCompile(`selectAuthMethod\('([^']+)'\)`)
+)
+
+func init() {
+	idp.Register("adfs", Provider{})
+}
+
+// Provider implements idp.Provider for Active Directory Federation Services.
+type Provider struct{}
+
+// Assertion logs in to ADFS and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from ADFS")
+
+	p, err := config.GetADFSProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetADFSApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c, err := NewClient(p.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("initializing ADFS client: %v", err)
+	}
+
+	user := p.Username
+	if user == "" {
+		user, err = prompt.Ask("ADFS username: ", r.Interactive)
+		if err != nil {
+			return "", fmt.Errorf("reading username: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	relyingParty := a.RelyingParty
+	if relyingParty == "" {
+		relyingParty = DefaultRelyingParty
+	}
+
+	log.WithFields(log.Fields{
+		"Username": user,
+		// print password only in Trace Log Level
+		"Password":     gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"RelyingParty": relyingParty,
+		"MFAMethod":    p.MFAMethod,
+	}).Debug("Logging in to ADFS")
+
+	return login(c, &loginParams{
+		RelyingParty: relyingParty,
+		Username:     user,
+		Password:     string(pass),
+		MFAMethod:    p.MFAMethod,
+		Interactive:  r.Interactive,
+	})
+}
+
+type loginParams struct {
+	RelyingParty string
+	Username     string
+	Password     string
+	MFAMethod    string
+	Interactive  bool
+}
+
+// login walks through the pages of the ADFS login flow until a page carrying the SAML response is
+// returned.
+func login(c *Client, p *loginParams) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	page, err := c.InitSSO(p.RelyingParty)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("starting login: %v", err)
+	}
+
+	var passwordSent, otpSent, methodSent bool
+	for i := 0; i < maxPages; i++ {
+		if assertion := page.SAMLResponse(); assertion != "" {
+			return assertion, nil
+		}
+
+		loginForm := page.Doc.Find(loginFormSelector).First()
+
+		switch {
+		case loginForm.Find(`input[name="Password"]`).Length() > 0:
+			if passwordSent {
+				return "", fmt.Errorf("login failed: %s", pageError(page))
+			}
+			passwordSent = true
+
+			form, err := page.Form(loginFormSelector)
+			if err != nil {
+				return "", err
+			}
+			form.Values.Set("UserName", p.Username)
+			form.Values.Set("Password", p.Password)
+			if form.Values.Get("AuthMethod") == "" {
+				form.Values.Set("AuthMethod", "FormsAuthentication")
+			}
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("submitting login form: %v", err)
+			}
+		case page.Doc.Find(optionsFormSelector).Length() > 0 && loginForm.Length() == 0:
+			if methodSent {
+				return "", fmt.Errorf("selecting MFA method failed: %s", pageError(page))
+			}
+			methodSent = true
+
+			form, err := page.Form(optionsFormSelector)
+			if err != nil {
+				return "", err
+			}
+			method, err := getAuthMethod(authMethods(page.Doc), p.MFAMethod)
+			if err != nil {
+				return "", err
+			}
+			log.WithField("AuthMethod", method).Debug("Selecting MFA method")
+			form.Values.Set("AuthMethod", method)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("selecting MFA method: %v", err)
+			}
+		case loginForm.Find(otpInputSelector).Length() > 0:
+			if otpSent {
+				return "", fmt.Errorf("MFA verification failed: %s", pageError(page))
+			}
+			otpSent = true
+
+			form, err := page.Form(loginFormSelector)
+			if err != nil {
+				return "", err
+			}
+			name := loginForm.Find(otpInputSelector).First().AttrOr("name", "")
+			log.WithFields(log.Fields{
+				"AuthMethod": form.Values.Get("AuthMethod"),
+				"input":      name,
+			}).Debug("MFA required")
+
+			otp, err := prompt.Ask("Please enter the OTP from your MFA device: ", p.Interactive)
+			if err != nil {
+				return "", fmt.Errorf("reading OTP: %v", err)
+			}
+			form.Values.Set(name, otp)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("verifying OTP: %v", err)
+			}
+		default:
+			return "", fmt.Errorf("unexpected login page: %s", pageError(page))
+		}
+	}
+
+	return "", errors.New("no SAML response received from ADFS")
+}
+
+// authMethods returns the MFA methods offered on an ADFS options page.
+func authMethods(doc *goquery.Document) []string {
+	var methods []string
+	doc.Find("[onclick]").Each(func(i int, s *goquery.Selection) {
+		if m := authMethodRegex.FindStringSubmatch(s.AttrOr("onclick", "")); m != nil {
+			methods = append(methods, m[1])
+		}
+	})
+	return methods
+}
+
+// getAuthMethod returns the preferred MFA method if it is offered, or the first offered method
+// otherwise.
+func getAuthMethod(methods []string, preferred string) (string, error) {
+	if len(methods) == 0 {
+		return "", errors.New("no MFA method offered by ADFS")
+	}
+	for _, m := range methods {
+		if m == preferred {
+			return m, nil
+		}
+	}
+	if preferred != "" {
+		log.Warnf("MFA method %s not offered by ADFS, using %s", preferred, methods[0])
+	}
+	return methods[0], nil
+}
+
+// pageError returns the error message shown on an ADFS page.
+func pageError(page *htmlform.Page) string {
+	msg := strings.TrimSpace(page.Doc.Find("#errorText, #error .fieldMargin, #errorMessage").First().Text())
+	if msg == "" {
+		return "unknown error"
+	}
+	return msg
+}
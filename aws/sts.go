@@ -49,24 +49,48 @@ func AssumeSAMLRole(PrincipalArn, RoleArn, SAMLAssertion, awsRegion string, dura
 	log.WithField("SAMLAssertion", SAMLAssertion).Trace("SAML assertion")
 	creds, err := assumeSAMLRole(PrincipalArn, RoleArn, SAMLAssertion, awsRegion, duration)
 	if err != nil {
-		// Check if API error returned by AWS
-		var ae smithy.APIError
-		if errors.As(err, &ae) {
-			// Check if error indicates exceeded duration, no structured error exists so check error message content.
-			if strings.Contains(ae.ErrorMessage(), "'durationSeconds' failed to satisfy constraint") || ae.ErrorMessage() == ErrInvalidSessionDuration {
-				// Return a custom error to allow the caller to retry etc.
-				// TODO Return a custom error type instead of a special value:
-				// https://dave.cheney.net/2014/12/24/inspecting-errors
-				return nil, errors.New(ErrDurationExceeded)
-			}
+		return nil, checkDurationExceeded(err)
+	}
 
-		}
-		return nil, err
+	return creds, nil
+}
+
+// AssumeRoleWithWebIdentity assumes an AWS IAM role using an OIDC ID token. Like AssumeSAMLRole,
+// it returns ErrDurationExceeded if the requested session duration is too long.
+func AssumeRoleWithWebIdentity(RoleArn, WebIdentityToken, sessionName, awsRegion string, duration int32) (*Credentials, error) {
+	log.WithFields(log.Fields{
+		"RoleArn":     RoleArn,
+		"sessionName": sessionName,
+		"awsRegion":   awsRegion,
+		"duration":    duration,
+	}).Debug("Assuming role with web identity")
+	log.WithField("WebIdentityToken", WebIdentityToken).Trace("Web identity token")
+	creds, err := assumeRoleWithWebIdentity(RoleArn, WebIdentityToken, sessionName, awsRegion, duration)
+	if err != nil {
+		return nil, checkDurationExceeded(err)
 	}
 
 	return creds, nil
 }
 
+// checkDurationExceeded returns ErrDurationExceeded if err indicates that the requested session
+// duration is higher than the maximum allowed on AWS, or err otherwise.
+func checkDurationExceeded(err error) error {
+	// Check if API error returned by AWS
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		// Check if error indicates exceeded duration, no structured error exists so check error message content.
+		if strings.Contains(ae.ErrorMessage(), "'durationSeconds' failed to satisfy constraint") || ae.ErrorMessage() == ErrInvalidSessionDuration {
+			// Return a custom error to allow the caller to retry etc.
+			// TODO Return a custom error type instead of a special value:
+			// https://dave.cheney.net/2014/12/24/inspecting-errors
+			return errors.New(ErrDurationExceeded)
+		}
+
+	}
+	return err
+}
+
 func assumeSAMLRole(PrincipalArn, RoleArn, SAMLAssertion, awsRegion string, duration int32) (*Credentials, error) {
 	input := sts.AssumeRoleWithSAMLInput{
 		PrincipalArn:    aws.String(PrincipalArn),
@@ -117,3 +141,54 @@ func assumeSAMLRole(PrincipalArn, RoleArn, SAMLAssertion, awsRegion string, dura
 
 	return &creds, nil
 }
+
+func assumeRoleWithWebIdentity(RoleArn, WebIdentityToken, sessionName, awsRegion string, duration int32) (*Credentials, error) {
+	input := sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(RoleArn),
+		RoleSessionName:  aws.String(sessionName),
+		WebIdentityToken: aws.String(WebIdentityToken),
+		DurationSeconds:  aws.Int32(duration),
+	}
+
+	ctx := context.Background()
+
+	// If we request credentials for China we need to provide a Chinese region
+	role := regexp.MustCompile(`^arn:aws-cn:iam::\d+:role\/\S+$`)
+	if role.MatchString(RoleArn) && !strings.HasPrefix(awsRegion, "cn-") {
+		log.Trace("Changing region to cn-north-1 as we are assuming a role in China")
+		awsRegion = "cn-north-1"
+	}
+	svc := sts.New(sts.Options{
+		Region: awsRegion,
+		// see https://github.com/aws/aws-sdk-go-v2/issues/2392 for reasoning
+		Credentials: nil,
+	})
+	log.WithField("awsRegion", awsRegion).Trace("Setup STS")
+
+	aResp, err := svc.AssumeRoleWithWebIdentity(ctx, &input)
+	if err != nil {
+		log.WithError(err).Debug("Error assuming role with web identity")
+		return nil, err
+	}
+
+	keyID := *aResp.Credentials.AccessKeyId
+	secretKey := *aResp.Credentials.SecretAccessKey
+	sessionToken := *aResp.Credentials.SessionToken
+	expiration := *aResp.Credentials.Expiration
+
+	log.WithFields(log.Fields{
+		"AccessKeyID":     keyID,
+		"SecretAccessKey": gog.If(log.GetLevel() == log.TraceLevel, secretKey, "<redacted>"),
+		"SessionToken":    gog.If(log.GetLevel() == log.TraceLevel, sessionToken, "<redacted>"),
+		"Expiration":      expiration,
+	}).Debug("Got temporary credentials")
+
+	creds := Credentials{
+		AccessKeyID:     keyID,
+		SecretAccessKey: secretKey,
+		SessionToken:    sessionToken,
+		Expiration:      expiration,
+	}
+
+	return &creds, nil
+}
@@ -0,0 +1,412 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package azuread
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/allcloud-io/clisso/htmlform"
+	"github.com/allcloud-io/clisso/log"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// DefaultLoginURL is the Entra ID login endpoint used when a provider doesn't configure one.
+	DefaultLoginURL = "https://login.microsoftonline.com"
+
+	// AWSSignInURL is the assertion consumer service of AWS, which Entra ID posts the SAML
+	// response to.
+	AWSSignInURL = "https://signin.aws.amazon.com/saml"
+
+	// Page IDs used by Entra ID to tell which step of the login flow a page represents.
+	PageSignIn = "ConvergedSignIn"
+	PageTFA    = "ConvergedTFA"
+	PageKMSI   = "KmsiInterrupt"
+
+	// Result values returned by the EndAuth endpoint.
+	ResultSuccess = "Success"
+	ResultPending = "AuthenticationPending"
+)
+
+// Client represents an Entra ID login client.
+type Client struct {
+	http.Client
+	// BaseURL is the Entra ID login endpoint, e.g. https://login.microsoftonline.com.
+	BaseURL string
+	// TenantID is the ID or domain of the Entra ID tenant.
+	TenantID string
+}
+
+// PageConfig represents the $Config object embedded into every page of the Entra ID login flow.
+type PageConfig struct {
+	PageID          string          `json:"pgid"`
+	FlowToken       string          `json:"sFT"`
+	Ctx             string          `json:"sCtx"`
+	Canary          string          `json:"canary"`
+	URLPost         string          `json:"urlPost"`
+	URLBeginAuth    string          `json:"urlBeginAuth"`
+	URLEndAuth      string          `json:"urlEndAuth"`
+	ErrorCode       json.RawMessage `json:"sErrorCode"`
+	ErrorText       string          `json:"sErrTxt"`
+	MaxPollAttempts int             `json:"iMaxPollAttempts"`
+	UserProofs      []UserProof     `json:"arrUserProofs"`
+}
+
+// Error returns the error code and text shown on the page, or an empty string if the page doesn't
+// report an error.
+func (p *PageConfig) Error() string {
+	code := strings.Trim(string(p.ErrorCode), `"`)
+	if code == "" || code == "null" || code == "0" {
+		return ""
+	}
+	if p.ErrorText != "" {
+		return fmt.Sprintf("%s: %s", code, p.ErrorText)
+	}
+	return code
+}
+
+// UserProof represents an MFA method the user is enrolled with.
+type UserProof struct {
+	AuthMethodID string `json:"authMethodId"`
+	Display      string `json:"display"`
+	IsDefault    bool   `json:"isDefault"`
+}
+
+// Page represents a page of the Entra ID login flow.
+type Page struct {
+	URL    *url.URL
+	Doc    *goquery.Document
+	Config *PageConfig
+}
+
+// SAMLResponse returns the SAML assertion carried by the page, if any.
+func (p *Page) SAMLResponse() string {
+	return htmlform.SAMLResponse(p.Doc)
+}
+
+// Start initiates an SP-initiated SAML login for the enterprise app identified by appIDURI and
+// returns the sign-in page.
+func (c *Client) Start(appIDURI string) (*Page, error) {
+	samlRequest, err := authnRequest(appIDURI, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("creating SAML request: %v", err)
+	}
+
+	u := fmt.Sprintf("%s/%s/saml2?%s", c.BaseURL, url.PathEscape(c.TenantID),
+		url.Values{"SAMLRequest": {samlRequest}}.Encode())
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	return c.doPage(req)
+}
+
+// LoginParams represents the parameters for Login.
+type LoginParams struct {
+	Username string
+	Password string
+}
+
+// Login posts the user's credentials to the sign-in page.
+func (c *Client) Login(page *Page, p *LoginParams) (*Page, error) {
+	return c.postPage(page, url.Values{
+		"login":     {p.Username},
+		"loginfmt":  {p.Username},
+		"passwd":    {p.Password},
+		"ctx":       {page.Config.Ctx},
+		"flowToken": {page.Config.FlowToken},
+		"canary":    {page.Config.Canary},
+		"type":      {"11"},
+	})
+}
+
+// KeepMeSignedIn answers the "Stay signed in?" page. The answer is always "no" since the session
+// isn't reused.
+func (c *Client) KeepMeSignedIn(page *Page) (*Page, error) {
+	return c.postPage(page, url.Values{
+		"LoginOptions": {"0"},
+		"ctx":          {page.Config.Ctx},
+		"flowToken":    {page.Config.FlowToken},
+		"canary":       {page.Config.Canary},
+		"type":         {"28"},
+	})
+}
+
+// AuthParams represents the parameters for BeginAuth and EndAuth.
+type AuthParams struct {
+	AuthMethodID       string `json:"AuthMethodId"`
+	Method             string `json:"Method"`
+	Ctx                string `json:"Ctx"`
+	FlowToken          string `json:"FlowToken"`
+	SessionID          string `json:"SessionId,omitempty"`
+	AdditionalAuthData string `json:"AdditionalAuthData,omitempty"`
+	PollCount          int    `json:"PollCount,omitempty"`
+}
+
+// AuthResponse represents the result of a call to BeginAuth or EndAuth.
+type AuthResponse struct {
+	Success      bool   `json:"Success"`
+	ResultValue  string `json:"ResultValue"`
+	Message      string `json:"Message"`
+	AuthMethodID string `json:"AuthMethodId"`
+	ErrCode      int    `json:"ErrCode"`
+	Retry        bool   `json:"Retry"`
+	FlowToken    string `json:"FlowToken"`
+	Ctx          string `json:"Ctx"`
+	SessionID    string `json:"SessionId"`
+	// Entropy is the number the user has to select in the Authenticator app when number matching
+	// is enforced.
+	Entropy int `json:"Entropy"`
+}
+
+// BeginAuth starts an MFA challenge using the given method. For push notifications this sends the
+// notification, for SMS this sends the text message.
+func (c *Client) BeginAuth(page *Page, authMethodID string) (*AuthResponse, error) {
+	return c.auth(page, page.Config.URLBeginAuth, &AuthParams{
+		AuthMethodID: authMethodID,
+		Method:       "BeginAuth",
+		Ctx:          page.Config.Ctx,
+		FlowToken:    page.Config.FlowToken,
+	})
+}
+
+// EndAuth completes an MFA challenge. For push notifications it has to be polled until the
+// ResultValue of the response is no longer ResultPending. For code based methods the code has to
+// be passed as AdditionalAuthData.
+func (c *Client) EndAuth(page *Page, p *AuthParams) (*AuthResponse, error) {
+	p.Method = "EndAuth"
+	return c.auth(page, page.Config.URLEndAuth, p)
+}
+
+// ProcessAuthParams represents the parameters for ProcessAuth.
+type ProcessAuthParams struct {
+	AuthMethodID string
+	Ctx          string
+	FlowToken    string
+	Username     string
+	OTP          string
+}
+
+// ProcessAuth submits a completed MFA challenge and returns the next page of the login flow.
+func (c *Client) ProcessAuth(page *Page, p *ProcessAuthParams) (*Page, error) {
+	v := url.Values{
+		"request":       {p.Ctx},
+		"mfaAuthMethod": {p.AuthMethodID},
+		"flowToken":     {p.FlowToken},
+		"canary":        {page.Config.Canary},
+		"login":         {p.Username},
+		"type":          {"22"},
+	}
+	if p.OTP != "" {
+		v.Set("otc", p.OTP)
+		v.Set("type", "19")
+	}
+	return c.postPage(page, v)
+}
+
+// SubmitForm submits the first form of the page as is. It is used for pages which the browser
+// would submit automatically using JavaScript.
+func (c *Client) SubmitForm(page *Page) (*Page, error) {
+	f, err := htmlform.Find(page.Doc, "form", page.URL)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, errors.New("page doesn't contain a form")
+	}
+
+	req, err := f.Request()
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	return c.doPage(req)
+}
+
+func (c *Client) auth(page *Page, endpoint string, p *AuthParams) (*AuthResponse, error) {
+	u, err := page.URL.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint %q: %v", endpoint, err)
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("parsing body: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("making HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("canary", page.Config.Canary)
+
+	data, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing HTTP request: %v", err)
+	}
+
+	var resp AuthResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+	return &resp, nil
+}
+
+func (c *Client) postPage(page *Page, v url.Values) (*Page, error) {
+	u, err := page.URL.Parse(page.Config.URLPost)
+	if err != nil {
+		return nil, fmt.Errorf("parsing post URL %q: %v", page.Config.URLPost, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("making HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.doPage(req)
+}
+
+// doPage executes the request and parses the returned login page.
+func (c *Client) doPage(req *http.Request) (*Page, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %v", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("loading HTML document: %v", err)
+	}
+
+	config, err := parseConfig(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing page config: %v", err)
+	}
+
+	return &Page{URL: resp.Request.URL, Doc: doc, Config: config}, nil
+}
+
+// doRequest gets a pointer to an HTTP request, executes it, handles any HTTP-related errors and
+// returns the response body.
+func (c *Client) doRequest(r *http.Request) ([]byte, error) {
+	resp, err := c.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// parseConfig extracts the $Config object from a login page. Pages without a $Config object
+// (e.g. the final page carrying the SAML response) yield an empty config.
+func parseConfig(body []byte) (*PageConfig, error) {
+	var config PageConfig
+
+	i := bytes.Index(body, []byte("$Config="))
+	if i < 0 {
+		return &config, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body[i+len("$Config="):]))
+	if err := dec.Decode(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// authnRequest returns a deflated, base64 encoded SAML AuthnRequest for the given app, suitable
+// for the HTTP-Redirect binding.
+func authnRequest(appIDURI string, now time.Time) (string, error) {
+	var issuer bytes.Buffer
+	if err := xml.EscapeText(&issuer, []byte(appIDURI)); err != nil {
+		return "", err
+	}
+
+	x := `<samlp:AuthnRequest xmlns="urn:oasis:names:tc:SAML:2.0:metadata" ` +
+		`ID="id` + strconv.FormatInt(now.UnixNano(), 16) + `" Version="2.0" ` +
+		`IssueInstant="` + now.UTC().Format(time.RFC3339) + `" IsPassive="false" ` +
+		`AssertionConsumerServiceURL="` + AWSSignInURL + `" ` +
+		`xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol">` +
+		`<Issuer xmlns="urn:oasis:names:tc:SAML:2.0:assertion">` + issuer.String() + `</Issuer>` +
+		`<samlp:NameIDPolicy Format="urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"/>` +
+		`</samlp:AuthnRequest>`
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write([]byte(x)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL, tenantID string) (*Client, error) {
+	// A cookie jar is required since the login flow keeps its state in session cookies.
+	options := cookiejar.Options{PublicSuffixList: publicsuffix.List}
+	jar, err := cookiejar.New(&options)
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %v", err)
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultLoginURL
+	}
+
+	c := &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), TenantID: tenantID}
+	c.Jar = jar
+
+	return c, nil
+}
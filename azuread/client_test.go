@@ -0,0 +1,132 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package azuread
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+func configPage(config string) string {
+	return fmt.Sprintf(`<html><head><script type="text/javascript">//<![CDATA[
+$Config=%s;
+//]]></script></head><body></body></html>`, config)
+}
+
+const samlPage = `<html><body><form method="POST" name="hiddenform" action="https://signin.aws.amazon.com/saml">
+<input type="hidden" name="SAMLResponse" value="fake_assertion" />
+<noscript><input type="submit" value="Click here to continue" /></noscript>
+</form></body></html>`
+
+// getTestServer returns a stand-in for the Entra ID login endpoints which walks through the login
+// page, a push notification MFA challenge and the "Stay signed in?" page.
+func getTestServer(t *testing.T) *httptest.Server {
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tenant/saml2", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("SAMLRequest"))
+		http.SetCookie(w, &http.Cookie{Name: "esctx", Value: "session"})
+		fmt.Fprint(w, configPage(`{"pgid":"ConvergedSignIn","sFT":"ft1","sCtx":"ctx1","canary":"c1","urlPost":"/tenant/login"}`))
+	})
+	mux.HandleFunc("/tenant/login", func(w http.ResponseWriter, r *http.Request) {
+		_, err := r.Cookie("esctx")
+		assert.Nil(t, err)
+		assert.Equal(t, "ctx1", r.PostFormValue("ctx"))
+		assert.Equal(t, "ft1", r.PostFormValue("flowToken"))
+		if r.PostFormValue("passwd") != "secret" {
+			fmt.Fprint(w, configPage(`{"pgid":"ConvergedSignIn","sErrorCode":"50126","sErrTxt":"Invalid username or password."}`))
+			return
+		}
+		fmt.Fprint(w, configPage(`{"pgid":"ConvergedTFA","sFT":"ft2","sCtx":"ctx2","canary":"c2",
+			"urlPost":"/common/SAS/ProcessAuth","urlBeginAuth":"/common/SAS/BeginAuth","urlEndAuth":"/common/SAS/EndAuth",
+			"arrUserProofs":[{"authMethodId":"PhoneAppOTP","display":"+X XXXXXXXX"},{"authMethodId":"PhoneAppNotification","display":"+X XXXXXXXX","isDefault":true}]}`))
+	})
+	mux.HandleFunc("/common/SAS/BeginAuth", func(w http.ResponseWriter, r *http.Request) {
+		var p AuthParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "BeginAuth", p.Method)
+		assert.Equal(t, MFAMethodPush, p.AuthMethodID)
+		fmt.Fprint(w, `{"Success":true,"ResultValue":"Success","SessionId":"sid","Ctx":"ctx3","FlowToken":"ft3","Entropy":42}`)
+	})
+	mux.HandleFunc("/common/SAS/EndAuth", func(w http.ResponseWriter, r *http.Request) {
+		var p AuthParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "EndAuth", p.Method)
+		assert.Equal(t, "sid", p.SessionID)
+		polls++
+		if polls < 2 {
+			fmt.Fprint(w, `{"Success":false,"ResultValue":"AuthenticationPending","Ctx":"ctx3","FlowToken":"ft3"}`)
+			return
+		}
+		fmt.Fprint(w, `{"Success":true,"ResultValue":"Success","Ctx":"ctx4","FlowToken":"ft4"}`)
+	})
+	mux.HandleFunc("/common/SAS/ProcessAuth", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ctx4", r.PostFormValue("request"))
+		assert.Equal(t, "ft4", r.PostFormValue("flowToken"))
+		fmt.Fprint(w, configPage(`{"pgid":"KmsiInterrupt","sFT":"ft5","sCtx":"ctx5","canary":"c5","urlPost":"/kmsi"}`))
+	})
+	mux.HandleFunc("/kmsi", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ft5", r.PostFormValue("flowToken"))
+		fmt.Fprint(w, samlPage)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestLogin(t *testing.T) {
+	mfaInterval = time.Millisecond
+
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, "tenant")
+	assert.Nil(t, err)
+
+	assertion, err := login(c, &loginParams{
+		AppIDURI: "https://signin.aws.amazon.com/saml",
+		Username: "user@example.com",
+		Password: "secret",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
+
+func TestLoginInvalidPassword(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, "tenant")
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{
+		AppIDURI: "https://signin.aws.amazon.com/saml",
+		Username: "user@example.com",
+		Password: "wrong",
+	})
+	assert.EqualError(t, err, "login failed with error 50126: Invalid username or password.")
+}
+
+func TestParseConfig(t *testing.T) {
+	c, err := parseConfig([]byte(configPage(`{"pgid":"ConvergedSignIn","sErrorCode":0,"urlPost":"/x?a=1;b=2"}`)))
+	assert.Nil(t, err)
+	assert.Equal(t, PageSignIn, c.PageID)
+	assert.Equal(t, "/x?a=1;b=2", c.URLPost)
+	assert.Equal(t, "", c.Error())
+
+	c, err = parseConfig([]byte(samlPage))
+	assert.Nil(t, err)
+	assert.Equal(t, "", c.PageID)
+}
@@ -0,0 +1,339 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package azuread
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/icza/gog"
+)
+
+const (
+	// MFAMethodPush symbolizes a Microsoft Authenticator push notification.
+	MFAMethodPush = "PhoneAppNotification"
+
+	// MFAMethodTOTP symbolizes a one-time password generated by an authenticator app.
+	MFAMethodTOTP = "PhoneAppOTP"
+
+	// MFAMethodSMS symbolizes a one-time password sent by text message.
+	MFAMethodSMS = "OneWaySMS"
+
+	// MFAMaxPollAttempts is the number of times a push notification is polled if the login page
+	// doesn't specify a limit.
+	MFAMaxPollAttempts = 60
+
+	// maxPages limits the number of pages followed during a single login.
+	maxPages = 10
+)
+
+var (
+	keyChain = keychain.DefaultKeychain{}
+
+	// mfaInterval represents the interval at which we check for an approved push notification.
+	mfaInterval = time.Second
+)
+
+func init() {
+	idp.Register("azuread", Provider{})
+}
+
+// Provider implements idp.Provider for Microsoft Entra ID (Azure AD).
+type Provider struct{}
+
+// Assertion logs in to Entra ID and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from Entra ID")
+
+	p, err := config.GetAzureADProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetAzureADApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c, err := NewClient(p.LoginURL, p.TenantID)
+	if err != nil {
+		return "", fmt.Errorf("initializing Entra ID client: %v", err)
+	}
+
+	user := p.Username
+	if user == "" {
+		user, err = prompt.Ask("Entra ID username: ", r.Interactive)
+		if err != nil {
+			return "", fmt.Errorf("reading username: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	log.WithFields(log.Fields{
+		"Username": user,
+		// print password only in Trace Log Level
+		"Password":  gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"AppIDURI":  a.AppIDURI,
+		"MFAMethod": p.MFAMethod,
+	}).Debug("Logging in to Entra ID")
+
+	return login(c, &loginParams{
+		AppIDURI:    a.AppIDURI,
+		Username:    user,
+		Password:    string(pass),
+		MFAMethod:   p.MFAMethod,
+		Interactive: r.Interactive,
+	})
+}
+
+type loginParams struct {
+	AppIDURI    string
+	Username    string
+	Password    string
+	MFAMethod   string
+	Interactive bool
+}
+
+// login walks through the pages of the Entra ID login flow until a page carrying the SAML
+// response is returned.
+func login(c *Client, p *loginParams) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	page, err := c.Start(p.AppIDURI)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("starting login: %v", err)
+	}
+
+	var loginSent bool
+	for i := 0; i < maxPages; i++ {
+		if assertion := page.SAMLResponse(); assertion != "" {
+			return assertion, nil
+		}
+
+		if e := page.Config.Error(); e != "" {
+			return "", fmt.Errorf("login failed with error %s", e)
+		}
+
+		log.WithField("pgid", page.Config.PageID).Trace("Processing login page")
+
+		switch page.Config.PageID {
+		case PageSignIn:
+			if loginSent {
+				return "", errors.New("login failed, please check username and password")
+			}
+			loginSent = true
+
+			s.Start()
+			page, err = c.Login(page, &LoginParams{Username: p.Username, Password: p.Password})
+			s.Stop()
+		case PageTFA:
+			page, err = verifyMFA(c, page, p)
+		case PageKMSI:
+			s.Start()
+			page, err = c.KeepMeSignedIn(page)
+			s.Stop()
+		case "ConvergedProofUpRedirect":
+			return "", errors.New("MFA registration is required, please log in using a browser first")
+		default:
+			if page.Config.PageID != "" {
+				return "", fmt.Errorf("unsupported login page '%s'", page.Config.PageID)
+			}
+			// Pages without a $Config object are auto-submitting forms, e.g. the "Working..."
+			// page shown when JavaScript is disabled.
+			s.Start()
+			page, err = c.SubmitForm(page)
+			s.Stop()
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", errors.New("no SAML response received from Entra ID")
+}
+
+// verifyMFA completes an MFA challenge and returns the next page of the login flow.
+func verifyMFA(c *Client, page *Page, p *loginParams) (*Page, error) {
+	proof, err := getProof(page.Config.UserProofs, p.MFAMethod, p.Interactive)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("AuthMethodID", proof.AuthMethodID).Debug("MFA required")
+
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	begin, err := c.BeginAuth(page, proof.AuthMethodID)
+	s.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("starting MFA challenge: %v", err)
+	}
+	if !begin.Success {
+		return nil, fmt.Errorf("starting MFA challenge failed: %s", begin.Message)
+	}
+
+	end := &AuthParams{
+		AuthMethodID: proof.AuthMethodID,
+		SessionID:    begin.SessionID,
+		Ctx:          begin.Ctx,
+		FlowToken:    begin.FlowToken,
+	}
+
+	var resp *AuthResponse
+	var otp string
+
+	switch proof.AuthMethodID {
+	case MFAMethodPush:
+		msg := "Please approve the request in the Microsoft Authenticator app"
+		if begin.Entropy != 0 {
+			msg = fmt.Sprintf("Please enter the number '%d' in the Microsoft Authenticator app", begin.Entropy)
+		}
+		fmt.Fprintln(prompt.Output(p.Interactive), msg)
+
+		attempts := page.Config.MaxPollAttempts
+		if attempts == 0 {
+			attempts = MFAMaxPollAttempts
+		}
+
+		s.Start()
+		for i := 1; ; i++ {
+			end.PollCount = i
+			resp, err = c.EndAuth(page, end)
+			if err != nil {
+				s.Stop()
+				return nil, fmt.Errorf("verifying MFA: %v", err)
+			}
+			if resp.ResultValue != ResultPending {
+				break
+			}
+			if i >= attempts {
+				s.Stop()
+				return nil, errors.New("MFA verification timed out")
+			}
+			end.Ctx, end.FlowToken = resp.Ctx, resp.FlowToken
+			time.Sleep(mfaInterval)
+		}
+		s.Stop()
+	case MFAMethodTOTP, MFAMethodSMS:
+		otp, err = prompt.Ask("Please enter the OTP from your MFA device: ", p.Interactive)
+		if err != nil {
+			return nil, fmt.Errorf("reading OTP: %v", err)
+		}
+		end.AdditionalAuthData = otp
+
+		s.Start()
+		resp, err = c.EndAuth(page, end)
+		s.Stop()
+		if err != nil {
+			return nil, fmt.Errorf("verifying MFA: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported MFA method '%s'", proof.AuthMethodID)
+	}
+
+	if !resp.Success || resp.ResultValue != ResultSuccess {
+		log.WithField("ResultValue", resp.ResultValue).Warn("MFA verification failed")
+		return nil, fmt.Errorf("MFA verification failed: %s", resp.ResultValue)
+	}
+
+	s.Start()
+	next, err := c.ProcessAuth(page, &ProcessAuthParams{
+		AuthMethodID: proof.AuthMethodID,
+		Ctx:          resp.Ctx,
+		FlowToken:    resp.FlowToken,
+		Username:     p.Username,
+		OTP:          otp,
+	})
+	s.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("processing MFA result: %v", err)
+	}
+
+	return next, nil
+}
+
+// getProof returns the MFA method to use out of the methods the user is enrolled with. A
+// configured preferred method is used if available, followed by the user's default method. If
+// neither applies, the user is prompted to select a method.
+func getProof(proofs []UserProof, preferred string, interactive bool) (*UserProof, error) {
+	supported := make([]UserProof, 0, len(proofs))
+	for _, p := range proofs {
+		switch p.AuthMethodID {
+		case MFAMethodPush, MFAMethodTOTP, MFAMethodSMS:
+			supported = append(supported, p)
+		default:
+			log.WithField("AuthMethodID", p.AuthMethodID).Trace("Skipping unsupported MFA method")
+		}
+	}
+
+	if len(supported) == 0 {
+		return nil, errors.New("no supported MFA method available")
+	}
+
+	if len(supported) == 1 {
+		return &supported[0], nil
+	}
+
+	if preferred != "" {
+		for i := range supported {
+			if supported[i].AuthMethodID == preferred {
+				return &supported[i], nil
+			}
+		}
+		// If the preferred method is not found, fall through and continue the selection process.
+		fmt.Fprintf(prompt.Output(interactive), "MFA method %s not found.\n", preferred)
+	} else {
+		for i := range supported {
+			if supported[i].IsDefault {
+				return &supported[i], nil
+			}
+		}
+	}
+
+	for {
+		for i, p := range supported {
+			fmt.Fprintf(prompt.Output(interactive), "%d. %s - %s\n", i+1, p.AuthMethodID, p.Display)
+		}
+
+		input, err := prompt.Ask(fmt.Sprintf("Please choose an MFA method to authenticate with (1-%d): ", len(supported)), interactive)
+		if err != nil {
+			return nil, fmt.Errorf("reading MFA method: %v", err)
+		}
+
+		// Verify we got an integer.
+		selection, err := strconv.Atoi(input)
+		if err != nil {
+			fmt.Fprintf(prompt.Output(interactive), "Invalid input '%s'\n", input)
+			continue
+		}
+
+		// Verify selection is within range.
+		if selection < 1 || selection > len(supported) {
+			fmt.Fprintf(prompt.Output(interactive), "Invalid value %d. Valid values: 1-%d\n", selection, len(supported))
+			continue
+		}
+
+		return &supported[selection-1], nil
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package azuread
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProof(t *testing.T) {
+	proofs := []UserProof{
+		{AuthMethodID: "TwoWayVoiceMobile"},
+		{AuthMethodID: MFAMethodTOTP},
+		{AuthMethodID: MFAMethodPush, IsDefault: true},
+		{AuthMethodID: MFAMethodSMS},
+	}
+
+	cases := []struct {
+		Name          string
+		Proofs        []UserProof
+		Preferred     string
+		Input         string
+		ExpectedProof *UserProof
+		ExpectedError error
+	}{
+		{
+			Name:          "NoProofs",
+			Proofs:        []UserProof{},
+			ExpectedError: errors.New("no supported MFA method available"),
+		},
+		{
+			Name:          "OnlyUnsupported",
+			Proofs:        []UserProof{{AuthMethodID: "TwoWayVoiceMobile"}},
+			ExpectedError: errors.New("no supported MFA method available"),
+		},
+		{
+			Name:          "SingleSupported",
+			Proofs:        []UserProof{{AuthMethodID: "TwoWayVoiceMobile"}, {AuthMethodID: MFAMethodSMS}},
+			ExpectedProof: &UserProof{AuthMethodID: MFAMethodSMS},
+		},
+		{
+			Name:          "Default",
+			Proofs:        proofs,
+			ExpectedProof: &UserProof{AuthMethodID: MFAMethodPush, IsDefault: true},
+		},
+		{
+			Name:          "Preferred",
+			Proofs:        proofs,
+			Preferred:     MFAMethodTOTP,
+			ExpectedProof: &UserProof{AuthMethodID: MFAMethodTOTP},
+		},
+		{
+			Name:          "Selected",
+			Proofs:        proofs,
+			Preferred:     "missing",
+			Input:         "3\n",
+			ExpectedProof: &UserProof{AuthMethodID: MFAMethodSMS},
+		},
+		{
+			Name:          "NoInput",
+			Proofs:        proofs,
+			Preferred:     "missing",
+			ExpectedError: errors.New("reading MFA method: EOF"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			testutil.WithStdin(t, c.Input, func() {
+				stdout, _ := testutil.CaptureOutput(t, func() {
+					p, err := getProof(c.Proofs, c.Preferred, false)
+					assert.Equal(t, c.ExpectedProof, p)
+					assert.Equal(t, c.ExpectedError, err)
+				})
+				// Nothing is printed to stdout when not interactive, since it may carry credentials.
+				assert.Empty(t, stdout)
+			})
+		})
+	}
+}
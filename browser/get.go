@@ -0,0 +1,93 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package browser
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/loopback"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+)
+
+const (
+	// DefaultPort is the port the ACS listener uses unless configured otherwise.
+	DefaultPort = 21843
+)
+
+var (
+	// LoginTimeout is the time the user has to complete the login in the browser.
+	LoginTimeout = 3 * time.Minute
+
+	// openURL opens the SSO URL in a browser. Replaced in tests.
+	openURL = loopback.Open
+)
+
+func init() {
+	idp.Register("browser", Provider{})
+}
+
+// Provider implements idp.Provider by letting the user log in to the IdP using a browser. The IdP
+// has to POST the SAML response to the local ACS listener.
+type Provider struct{}
+
+// Assertion opens the SSO URL of the app in a browser and waits for the SAML response.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion using a browser")
+
+	p, err := config.GetBrowserProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetBrowserApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	port := p.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	return capture(a.URL, port, r.Interactive)
+}
+
+// capture starts the ACS listener, opens the SSO URL and returns the SAML response received by
+// the listener.
+func capture(ssoURL string, port int, interactive bool) (string, error) {
+	l, err := Listen(port)
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+
+	log.WithFields(log.Fields{
+		"url": ssoURL,
+		"acs": l.URL(),
+	}).Debug("Waiting for SAML response")
+
+	// Print to stderr since stdout may carry the credentials.
+	fmt.Fprintf(os.Stderr, "Please log in using your browser. If it doesn't open, visit:\n%s\n", ssoURL)
+	if err = openURL(ssoURL); err != nil {
+		log.WithError(err).Debug("Could not open browser")
+	}
+
+	s := spinner.New(interactive)
+	s.Start()
+	assertion, err := l.Wait(LoginTimeout)
+	s.Stop()
+
+	return assertion, err
+}
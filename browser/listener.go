@@ -0,0 +1,41 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package browser
+
+import (
+	"net/http"
+
+	"github.com/allcloud-io/clisso/internal/loopback"
+	"github.com/allcloud-io/clisso/log"
+)
+
+const (
+	// ACSPath is the path the IdP has to POST the SAML response to.
+	ACSPath = "/saml"
+)
+
+// Listen starts an ACS listener on 127.0.0.1 and the given port. Port 0 picks a free port.
+func Listen(port int) (*loopback.Listener, error) {
+	return loopback.NewListener(port, ACSPath, "SAML response", handleACS)
+}
+
+// handleACS receives the SAML response POSTed by the browser.
+func handleACS(w http.ResponseWriter, r *http.Request) *loopback.Result {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil
+	}
+
+	assertion := r.PostFormValue("SAMLResponse")
+	if assertion == "" {
+		http.Error(w, "no SAMLResponse received", http.StatusBadRequest)
+		return nil
+	}
+	log.Trace("SAML response received from browser")
+
+	return &loopback.Result{Value: assertion}
+}
@@ -0,0 +1,77 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package browser
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/internal/loopback"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+func TestListener(t *testing.T) {
+	l, err := Listen(0)
+	assert.Nil(t, err)
+	defer l.Close()
+
+	resp, err := http.Get(l.URL())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.PostForm(l.URL(), url.Values{"RelayState": {""}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.PostForm(l.URL(), url.Values{"SAMLResponse": {"fake_assertion"}})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assertion, err := l.Wait(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
+
+func TestListenerTimeout(t *testing.T) {
+	l, err := Listen(0)
+	assert.Nil(t, err)
+	defer l.Close()
+
+	_, err = l.Wait(time.Millisecond)
+	assert.EqualError(t, err, "timeout waiting for the SAML response from the browser")
+}
+
+func TestCapture(t *testing.T) {
+	// Pick a free port for the listener.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	// Instead of opening a browser, POST the assertion like the IdP would.
+	openURL = func(ssoURL string) error {
+		assert.Equal(t, "https://idp.example.com/sso", ssoURL)
+		go func() {
+			resp, err := http.PostForm("http://127.0.0.1:"+strconv.Itoa(port)+ACSPath,
+				url.Values{"SAMLResponse": {"fake_assertion"}})
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+		return nil
+	}
+	defer func() { openURL = loopback.Open }()
+
+	assertion, err := capture("https://idp.example.com/sso", port, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
@@ -7,12 +7,18 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/allcloud-io/clisso/internal/prompt"
 	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/okta"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 // Common
@@ -26,7 +32,34 @@ var appID string
 // URL holds the Okta URL
 var URL string
 
+// Okta app discovery
+var discoverAll bool
+var dryRun bool
+
+// Entra ID (Azure AD)
+var appIDURI string
+
+// ADFS
+var relyingParty string
+
+// PingFederate
+var partnerSpID string
+
+// JumpCloud
+var appSlug string
+
+// IAM Identity Center
+var accountID string
+var roleName string
+
+// OIDC
+var roleARN string
+
 func init() {
+	// Okta app discovery
+	cmdAppsDiscover.Flags().BoolVar(&discoverAll, "all", false, "Create apps for all discovered AWS apps without asking")
+	cmdAppsDiscover.Flags().BoolVar(&dryRun, "dry-run", false, "Print the apps as YAML instead of saving them")
+
 	// OneLogin
 	cmdAppsCreateOneLogin.Flags().StringVar(&appID, "app-id", "", "OneLogin app ID")
 	cmdAppsCreateOneLogin.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
@@ -42,12 +75,102 @@ func init() {
 	mandatoryFlag(cmdAppsCreateOkta, "provider")
 	mandatoryFlag(cmdAppsCreateOkta, "url")
 
+	// Entra ID (Azure AD)
+	cmdAppsCreateAzureAD.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateAzureAD.Flags().StringVar(&appIDURI, "app-id-uri", "",
+		"Identifier (Entity ID) of the AWS enterprise app")
+	cmdAppsCreateAzureAD.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateAzureAD.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateAzureAD, "provider")
+	mandatoryFlag(cmdAppsCreateAzureAD, "app-id-uri")
+
+	// Google Workspace
+	cmdAppsCreateGoogle.Flags().StringVar(&appID, "app-id", "", "Google Workspace SAML app ID (spid)")
+	cmdAppsCreateGoogle.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateGoogle.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateGoogle.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateGoogle, "app-id")
+	mandatoryFlag(cmdAppsCreateGoogle, "provider")
+
+	// Keycloak
+	cmdAppsCreateKeycloak.Flags().StringVar(&clientID, "client-id", "",
+		"IDP-initiated SSO URL name of the Keycloak client")
+	cmdAppsCreateKeycloak.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateKeycloak.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateKeycloak.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateKeycloak, "client-id")
+	mandatoryFlag(cmdAppsCreateKeycloak, "provider")
+
+	// ADFS
+	cmdAppsCreateADFS.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateADFS.Flags().StringVar(&relyingParty, "relying-party", "",
+		"(Optional) Identifier of the relying party trust (default urn:amazon:webservices)")
+	cmdAppsCreateADFS.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateADFS.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateADFS, "provider")
+
+	// PingFederate
+	cmdAppsCreatePing.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreatePing.Flags().StringVar(&partnerSpID, "partner-sp-id", "",
+		"(Optional) Entity ID of the SP connection (default urn:amazon:webservices)")
+	cmdAppsCreatePing.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreatePing.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreatePing, "provider")
+
+	// JumpCloud
+	cmdAppsCreateJumpCloud.Flags().StringVar(&appSlug, "app-slug", "", "IdP URL slug of the JumpCloud SSO application")
+	cmdAppsCreateJumpCloud.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateJumpCloud.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateJumpCloud.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateJumpCloud, "app-slug")
+	mandatoryFlag(cmdAppsCreateJumpCloud, "provider")
+
+	// Browser
+	cmdAppsCreateBrowser.Flags().StringVar(&URL, "url", "", "IdP-initiated SSO URL of the app")
+	cmdAppsCreateBrowser.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateBrowser.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateBrowser.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateBrowser, "url")
+	mandatoryFlag(cmdAppsCreateBrowser, "provider")
+
+	// IAM Identity Center
+	cmdAppsCreateIdentityCenter.Flags().StringVar(&accountID, "account-id", "", "AWS account ID")
+	cmdAppsCreateIdentityCenter.Flags().StringVar(&roleName, "role-name", "", "Name of the permission set to use")
+	cmdAppsCreateIdentityCenter.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	mandatoryFlag(cmdAppsCreateIdentityCenter, "account-id")
+	mandatoryFlag(cmdAppsCreateIdentityCenter, "role-name")
+	mandatoryFlag(cmdAppsCreateIdentityCenter, "provider")
+
+	// OIDC
+	cmdAppsCreateOIDC.Flags().StringVar(&roleARN, "role-arn", "", "ARN of the role to assume")
+	cmdAppsCreateOIDC.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateOIDC.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	mandatoryFlag(cmdAppsCreateOIDC, "role-arn")
+	mandatoryFlag(cmdAppsCreateOIDC, "provider")
+
+	// Exec
+	cmdAppsCreateExec.Flags().StringVar(&provider, "provider", "", "Name of the Clisso provider")
+	cmdAppsCreateExec.Flags().IntVar(&duration, "duration", 0, "(Optional) Session duration in seconds")
+	cmdAppsCreateExec.Flags().StringVar(&arn, "arn", "", "(Optional) preferred arn for app")
+	mandatoryFlag(cmdAppsCreateExec, "provider")
+
 	// Build command tree
 	RootCmd.AddCommand(cmdApps)
 	cmdApps.AddCommand(cmdAppsList)
 	cmdApps.AddCommand(cmdAppsCreate)
 	cmdAppsCreate.AddCommand(cmdAppsCreateOneLogin)
 	cmdAppsCreate.AddCommand(cmdAppsCreateOkta)
+	cmdAppsCreate.AddCommand(cmdAppsCreateAzureAD)
+	cmdAppsCreate.AddCommand(cmdAppsCreateGoogle)
+	cmdAppsCreate.AddCommand(cmdAppsCreateKeycloak)
+	cmdAppsCreate.AddCommand(cmdAppsCreateADFS)
+	cmdAppsCreate.AddCommand(cmdAppsCreatePing)
+	cmdAppsCreate.AddCommand(cmdAppsCreateJumpCloud)
+	cmdAppsCreate.AddCommand(cmdAppsCreateBrowser)
+	cmdAppsCreate.AddCommand(cmdAppsCreateIdentityCenter)
+	cmdAppsCreate.AddCommand(cmdAppsCreateOIDC)
+	cmdAppsCreate.AddCommand(cmdAppsCreateExec)
+	cmdApps.AddCommand(cmdAppsDiscover)
 	cmdApps.AddCommand(cmdAppsSelect)
 	cmdApps.AddCommand(cmdAppsDelete)
 }
@@ -204,6 +327,722 @@ var cmdAppsCreateOkta = &cobra.Command{
 	},
 }
 
+var cmdAppsCreateAzureAD = &cobra.Command{
+	Use:   "azuread [app name]",
+	Short: "Create a new Entra ID (Azure AD) app",
+	Long:  "Save a new Microsoft Entra ID (Azure AD) app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "azuread" {
+			log.Fatalf(
+				"Invalid provider type '%s' for an Entra ID app. Type must be 'azuread'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"app-id-uri": appIDURI,
+			"provider":   provider,
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateGoogle = &cobra.Command{
+	Use:   "google [app name]",
+	Short: "Create a new Google Workspace app",
+	Long:  "Save a new Google Workspace app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "google" {
+			log.Fatalf(
+				"Invalid provider type '%s' for a Google Workspace app. Type must be 'google'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"app-id":   appID,
+			"provider": provider,
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateKeycloak = &cobra.Command{
+	Use:   "keycloak [app name]",
+	Short: "Create a new Keycloak app",
+	Long:  "Save a new Keycloak app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "keycloak" {
+			log.Fatalf(
+				"Invalid provider type '%s' for a Keycloak app. Type must be 'keycloak'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"client-id": clientID,
+			"provider":  provider,
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateADFS = &cobra.Command{
+	Use:   "adfs [app name]",
+	Short: "Create a new ADFS app",
+	Long:  "Save a new Active Directory Federation Services app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "adfs" {
+			log.Fatalf(
+				"Invalid provider type '%s' for an ADFS app. Type must be 'adfs'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"provider": provider,
+		}
+
+		if relyingParty != "" {
+			conf["relying-party"] = relyingParty
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreatePing = &cobra.Command{
+	Use:   "ping [app name]",
+	Short: "Create a new PingFederate app",
+	Long:  "Save a new PingFederate app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "ping" {
+			log.Fatalf(
+				"Invalid provider type '%s' for a PingFederate app. Type must be 'ping'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"provider": provider,
+		}
+
+		if partnerSpID != "" {
+			conf["partner-sp-id"] = partnerSpID
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateJumpCloud = &cobra.Command{
+	Use:   "jumpcloud [app name]",
+	Short: "Create a new JumpCloud app",
+	Long:  "Save a new JumpCloud app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "jumpcloud" {
+			log.Fatalf(
+				"Invalid provider type '%s' for a JumpCloud app. Type must be 'jumpcloud'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"app-slug": appSlug,
+			"provider": provider,
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateBrowser = &cobra.Command{
+	Use:   "browser [app name]",
+	Short: "Create a new browser app",
+	Long:  "Save a new app which is logged in to using a browser into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "browser" {
+			log.Fatalf(
+				"Invalid provider type '%s' for a browser app. Type must be 'browser'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"provider": provider,
+			"url":      URL,
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateIdentityCenter = &cobra.Command{
+	Use:   "identity-center [app name]",
+	Short: "Create a new IAM Identity Center app",
+	Long:  "Save a new AWS IAM Identity Center account and permission set into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "identity-center" {
+			log.Fatalf(
+				"Invalid provider type '%s' for an IAM Identity Center app. Type must be 'identity-center'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"account-id": accountID,
+			"provider":   provider,
+			"role-name":  roleName,
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateOIDC = &cobra.Command{
+	Use:   "oidc [app name]",
+	Short: "Create a new OIDC app",
+	Long:  "Save a new OpenID Connect app into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "oidc" {
+			log.Fatalf(
+				"Invalid provider type '%s' for an OIDC app. Type must be 'oidc'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"provider": provider,
+			"role-arn": roleARN,
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsCreateExec = &cobra.Command{
+	Use:   "exec [app name]",
+	Short: "Create a new app using an external helper",
+	Long: `Save a new app using a provider backed by an external helper into the config file.
+
+Any other settings the helper needs can be added to the app in the config file, they are passed to
+the helper along with the request.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify app doesn't exist
+		if exists := viper.Get("apps." + name); exists != nil {
+			log.Fatalf("App '%s' already exists", name)
+		}
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "exec" {
+			log.Fatalf(
+				"Invalid provider type '%s' for an exec app. Type must be 'exec'.",
+				pType,
+			)
+		}
+
+		conf := map[string]string{
+			"provider": provider,
+		}
+
+		if arn != "" {
+			conf["arn"] = arn
+		}
+
+		if duration != 0 {
+			// Duration specified - validate value
+			if duration < 3600 || duration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			log.Tracef("Setting duration to %d", duration)
+			conf["duration"] = strconv.Itoa(duration)
+		}
+
+		viper.Set(fmt.Sprintf("apps.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("App '%s' saved to config file", name)
+	},
+}
+
+var cmdAppsDiscover = &cobra.Command{
+	Use:   "discover [provider name]",
+	Short: "Create apps for the AWS apps assigned in Okta",
+	Long: `Log in to an Okta provider, list the AWS apps assigned to the user and save the selected
+ones into the config file. App names are generated from the app labels.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := args[0]
+
+		// Verify provider exists
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Verify provider type
+		pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider))
+		if pType != "okta" {
+			log.Fatalf("Invalid provider type '%s'. Apps can only be discovered for 'okta' providers.", pType)
+		}
+
+		links, err := okta.AWSAppLinks(provider, true)
+		if err != nil {
+			log.Fatalf("Could not discover apps: %v", err)
+		}
+
+		// Skip apps which are already configured
+		configured := map[string]string{}
+		for name := range viper.GetStringMap("apps") {
+			if viper.GetString(fmt.Sprintf("apps.%s.provider", name)) == provider {
+				configured[viper.GetString(fmt.Sprintf("apps.%s.url", name))] = name
+			}
+		}
+		var newLinks []okta.AppLink
+		for _, l := range links {
+			if name, ok := configured[l.LinkURL]; ok {
+				log.Printf("Skipping '%s', already configured as app '%s'", l.Label, name)
+				continue
+			}
+			newLinks = append(newLinks, l)
+		}
+
+		if len(newLinks) == 0 {
+			log.Println("No new AWS apps found")
+			return
+		}
+
+		selected := newLinks
+		if !discoverAll {
+			selected, err = selectLinks(newLinks)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		taken := map[string]bool{}
+		for name := range viper.GetStringMap("apps") {
+			taken[name] = true
+		}
+		apps := map[string]map[string]string{}
+		for _, l := range selected {
+			name := discoveredAppName(l.Label, taken)
+			taken[name] = true
+			apps[name] = map[string]string{
+				"provider": provider,
+				"url":      l.LinkURL,
+			}
+		}
+
+		if dryRun {
+			out, err := yaml.Marshal(map[string]interface{}{"apps": apps})
+			if err != nil {
+				log.Fatalf("Error printing apps: %v", err)
+			}
+			fmt.Print(string(out))
+			return
+		}
+
+		names := make([]string, 0, len(apps))
+		for name, conf := range apps {
+			viper.Set(fmt.Sprintf("apps.%s", name), conf)
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// Write config to file
+		err = viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		for _, name := range names {
+			log.Printf("App '%s' saved to config file", name)
+		}
+	},
+}
+
+// selectLinks lists the links and returns the ones the user selects. The answer is read through
+// the prompt package, since the Okta login may have buffered stdin already.
+func selectLinks(links []okta.AppLink) ([]okta.AppLink, error) {
+	for i, l := range links {
+		fmt.Fprintf(os.Stderr, "%d. %s - %s\n", i+1, l.Label, l.LinkURL)
+	}
+	fmt.Fprintf(os.Stderr, "Please choose the apps to create (e.g. 1,3 or all): ")
+	input, err := prompt.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("reading input: %v", err)
+	}
+
+	idx, err := parseSelection(input, len(links))
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]okta.AppLink, 0, len(idx))
+	for _, i := range idx {
+		selected = append(selected, links[i])
+	}
+	return selected, nil
+}
+
+// parseSelection parses a comma separated list of numbers between 1 and n, or "all", and returns
+// the selected indices.
+func parseSelection(input string, n int) ([]int, error) {
+	if strings.EqualFold(input, "all") {
+		idx := make([]int, n)
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx, nil
+	}
+
+	var idx []int
+	seen := map[int]bool{}
+	for _, s := range strings.Split(input, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid input '%s'", s)
+		}
+		if i < 1 || i > n {
+			return nil, fmt.Errorf("invalid value %d. Valid values: 1-%d", i, n)
+		}
+		if !seen[i] {
+			idx = append(idx, i-1)
+			seen[i] = true
+		}
+	}
+	return idx, nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// discoveredAppName generates an app name from an Okta app label, e.g. "AWS Prod (Admin)" becomes
+// "aws-prod-admin". A number is appended if the name is taken.
+func discoveredAppName(label string, taken map[string]bool) string {
+	base := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(label), "-"), "-")
+	if base == "" {
+		base = "okta-app"
+	}
+
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
 var cmdAppsSelect = &cobra.Command{
 	Use:   "select [app name]",
 	Short: "Select an app to be used by default",
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/okta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelection(t *testing.T) {
+	idx, err := parseSelection("all", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 1, 2}, idx)
+
+	idx, err = parseSelection("3, 1,3", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 0}, idx)
+
+	_, err = parseSelection("4", 3)
+	assert.EqualError(t, err, "invalid value 4. Valid values: 1-3")
+
+	_, err = parseSelection("one", 3)
+	assert.EqualError(t, err, "invalid input 'one'")
+}
+
+func TestSelectLinks(t *testing.T) {
+	links := []okta.AppLink{{Label: "AWS Dev"}, {Label: "AWS Prod"}, {Label: "AWS Test"}}
+
+	testutil.WithStdin(t, "1, 3\n", func() {
+		stdout, _ := testutil.CaptureOutput(t, func() {
+			selected, err := selectLinks(links)
+			assert.Nil(t, err)
+			assert.Equal(t, []okta.AppLink{links[0], links[2]}, selected)
+		})
+		assert.Empty(t, stdout)
+	})
+}
+
+func TestDiscoveredAppName(t *testing.T) {
+	taken := map[string]bool{"aws-prod": true, "aws-prod-2": true}
+
+	assert.Equal(t, "aws-dev-admin", discoveredAppName("AWS Dev (Admin)", taken))
+	assert.Equal(t, "aws-prod-3", discoveredAppName("AWS Prod", taken))
+	assert.Equal(t, "okta-app", discoveredAppName("ÄÖÜ", taken))
+}
@@ -6,6 +6,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,8 +17,7 @@ import (
 	"github.com/mitchellh/go-homedir"
 
 	"github.com/allcloud-io/clisso/aws"
-	"github.com/allcloud-io/clisso/okta"
-	"github.com/allcloud-io/clisso/onelogin"
+	"github.com/allcloud-io/clisso/idp"
 	"github.com/nightlyone/lockfile"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -272,28 +272,26 @@ If no app is specified, the selected app (if configured) will be assumed.`,
 		checkCredentialProcessActive(printToCredentialProcess)
 
 		interactive := !printToShell && !printToCredentialProcess
-		if pType == "onelogin" {
-			creds, err := onelogin.Get(app, provider, pArn, awsRegion, duration, interactive)
-			if err != nil {
-				log.Fatal("Could not get temporary credentials: ", err)
-			}
-			// Process credentials
-			err = processCredentials(creds, app)
-			if err != nil {
-				log.Fatalf("Error processing credentials: %v", err)
-			}
-		} else if pType == "okta" {
-			creds, err := okta.Get(app, provider, pArn, awsRegion, duration, interactive)
-			if err != nil {
-				log.Fatal("Could not get temporary credentials: ", err)
-			}
-			// Process credentials
-			err = processCredentials(creds, app)
-			if err != nil {
-				log.Fatalf("Error processing credentials: %v", err)
-			}
-		} else {
-			log.Fatalf("Unsupported identity provider type '%s' for app '%s'", pType, app)
+		creds, err := idp.Get(pType, &idp.Request{
+			App:          app,
+			Provider:     provider,
+			PreferredARN: pArn,
+			AWSRegion:    awsRegion,
+			Duration:     duration,
+			Interactive:  interactive,
+		})
+		if errors.Is(err, idp.ErrCanceled) {
+			// Exit like a command terminated by an interrupt
+			unlock()
+			os.Exit(130)
+		}
+		if err != nil {
+			log.Fatal("Could not get temporary credentials: ", err)
+		}
+		// Process credentials
+		err = processCredentials(creds, app)
+		if err != nil {
+			log.Fatalf("Error processing credentials: %v", err)
 		}
 		if interactive {
 			printStatus()
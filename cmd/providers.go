@@ -9,13 +9,28 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/allcloud-io/clisso/keychain"
 	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/oidc"
+	"github.com/allcloud-io/clisso/okta"
+	"github.com/allcloud-io/clisso/onelogin"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/term"
+
+	// Identity providers register themselves with the idp package.
+	_ "github.com/allcloud-io/clisso/adfs"
+	_ "github.com/allcloud-io/clisso/azuread"
+	_ "github.com/allcloud-io/clisso/browser"
+	_ "github.com/allcloud-io/clisso/exec"
+	_ "github.com/allcloud-io/clisso/google"
+	_ "github.com/allcloud-io/clisso/identitycenter"
+	_ "github.com/allcloud-io/clisso/jumpcloud"
+	_ "github.com/allcloud-io/clisso/keycloak"
+	_ "github.com/allcloud-io/clisso/ping"
 )
 
 // OneLogin
@@ -24,10 +39,47 @@ var clientSecret string
 var subdomain string
 var username string
 var region string
+var apiBaseURL string
 var providerDuration int
 
 // Okta
 var baseURL string
+var mfaFactor string
+var mfaPushTimeout int
+var mfaInterval int
+var authFlow string
+
+// Entra ID (Azure AD)
+var tenantID string
+var loginURL string
+var mfaMethod string
+
+// Google Workspace
+var idpID string
+
+// Keycloak
+var realm string
+
+// Browser
+var port int
+
+// IAM Identity Center
+var startURL string
+var ssoRegion string
+var oidcURL string
+var portalURL string
+
+// JumpCloud
+var ssoURL string
+
+// OIDC
+var issuer string
+var scope string
+var flow string
+
+// Exec
+var command string
+var commandArgs []string
 
 func init() {
 	// OneLogin
@@ -40,6 +92,8 @@ func init() {
 		"Don't ask for a username and use this instead")
 	cmdProvidersCreateOneLogin.Flags().StringVar(&region, "region", "US",
 		"Region in which the OneLogin API lives")
+	cmdProvidersCreateOneLogin.Flags().StringVar(&apiBaseURL, "api-base-url", "",
+		"(Optional) Base URL of the OneLogin API, used instead of the region's API. Must use HTTPS unless the host is a loopback address")
 	cmdProvidersCreateOneLogin.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
 
 	mandatoryFlag(cmdProvidersCreateOneLogin, "client-id")
@@ -50,17 +104,140 @@ func init() {
 	cmdProvidersCreateOkta.Flags().StringVar(&baseURL, "base-url", "", "Okta base URL")
 	cmdProvidersCreateOkta.Flags().StringVar(&username, "username", "",
 		"Don't ask for a username and use this instead")
+	cmdProvidersCreateOkta.Flags().StringVar(&mfaFactor, "mfa-factor", "",
+		"(Optional) Preferred MFA factor type, optionally prefixed with the factor provider, e.g. push or GOOGLE:token:software:totp")
+	cmdProvidersCreateOkta.Flags().IntVar(&mfaPushTimeout, "mfa-push-timeout", 0,
+		"(Optional) Seconds to wait for a push to be approved before falling back to TOTP (default 60)")
+	cmdProvidersCreateOkta.Flags().IntVar(&mfaInterval, "mfa-interval", 0,
+		"(Optional) Seconds between checks for an approved push or a clicked email link (default 2)")
+	cmdProvidersCreateOkta.Flags().StringVar(&authFlow, "auth-flow", "",
+		"(Optional) Authentication flow to use, classic, idx or device (classic or idx is detected from the org by default)")
+	cmdProvidersCreateOkta.Flags().StringVar(&clientID, "client-id", "",
+		"(Optional) Client ID of the Okta native app used by the device auth flow")
 	cmdProvidersCreateOkta.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
 
 	mandatoryFlag(cmdProvidersCreateOkta, "base-url")
 
+	// Entra ID (Azure AD)
+	cmdProvidersCreateAzureAD.Flags().StringVar(&tenantID, "tenant-id", "", "Entra ID tenant ID or domain")
+	cmdProvidersCreateAzureAD.Flags().StringVar(&username, "username", "",
+		"Don't ask for a username and use this instead")
+	cmdProvidersCreateAzureAD.Flags().StringVar(&loginURL, "login-url", "",
+		"(Optional) Entra ID login URL (default https://login.microsoftonline.com)")
+	cmdProvidersCreateAzureAD.Flags().StringVar(&mfaMethod, "mfa-method", "",
+		"(Optional) Preferred MFA method: PhoneAppNotification, PhoneAppOTP or OneWaySMS")
+	cmdProvidersCreateAzureAD.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreateAzureAD, "tenant-id")
+
+	// Google Workspace
+	cmdProvidersCreateGoogle.Flags().StringVar(&idpID, "idp-id", "", "Google Workspace IdP ID")
+	cmdProvidersCreateGoogle.Flags().StringVar(&username, "username", "",
+		"Don't ask for a username and use this instead")
+	cmdProvidersCreateGoogle.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreateGoogle, "idp-id")
+
+	// Keycloak
+	cmdProvidersCreateKeycloak.Flags().StringVar(&baseURL, "base-url", "", "Keycloak base URL")
+	cmdProvidersCreateKeycloak.Flags().StringVar(&realm, "realm", "", "Keycloak realm")
+	cmdProvidersCreateKeycloak.Flags().StringVar(&username, "username", "",
+		"Don't ask for a username and use this instead")
+	cmdProvidersCreateKeycloak.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreateKeycloak, "base-url")
+	mandatoryFlag(cmdProvidersCreateKeycloak, "realm")
+
+	// ADFS
+	cmdProvidersCreateADFS.Flags().StringVar(&baseURL, "base-url", "", "ADFS base URL")
+	cmdProvidersCreateADFS.Flags().StringVar(&username, "username", "",
+		"Don't ask for a username and use this instead")
+	cmdProvidersCreateADFS.Flags().StringVar(&mfaMethod, "mfa-method", "",
+		"(Optional) Preferred ADFS MFA adapter, e.g. AzureMfaAuthentication")
+	cmdProvidersCreateADFS.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreateADFS, "base-url")
+
+	// PingFederate
+	cmdProvidersCreatePing.Flags().StringVar(&baseURL, "base-url", "", "PingFederate base URL")
+	cmdProvidersCreatePing.Flags().StringVar(&username, "username", "",
+		"Don't ask for a username and use this instead")
+	cmdProvidersCreatePing.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreatePing, "base-url")
+
+	// JumpCloud
+	cmdProvidersCreateJumpCloud.Flags().StringVar(&username, "username", "",
+		"Don't ask for an email address and use this instead")
+	cmdProvidersCreateJumpCloud.Flags().StringVar(&mfaMethod, "mfa-method", "",
+		"(Optional) Preferred MFA factor, totp or push")
+	cmdProvidersCreateJumpCloud.Flags().StringVar(&baseURL, "base-url", "",
+		"(Optional) JumpCloud user portal URL (default https://console.jumpcloud.com)")
+	cmdProvidersCreateJumpCloud.Flags().StringVar(&ssoURL, "sso-url", "",
+		"(Optional) JumpCloud SSO URL (default https://sso.jumpcloud.com)")
+	cmdProvidersCreateJumpCloud.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	// Browser
+	cmdProvidersCreateBrowser.Flags().IntVar(&port, "port", 0,
+		"(Optional) Port of the local ACS listener (default 21843)")
+	cmdProvidersCreateBrowser.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	// IAM Identity Center
+	cmdProvidersCreateIdentityCenter.Flags().StringVar(&startURL, "start-url", "",
+		"AWS access portal URL, e.g. https://my-company.awsapps.com/start")
+	cmdProvidersCreateIdentityCenter.Flags().StringVar(&ssoRegion, "region", "",
+		"AWS region IAM Identity Center is enabled in")
+	cmdProvidersCreateIdentityCenter.Flags().StringVar(&oidcURL, "oidc-url", "",
+		"(Optional) Override the SSO-OIDC endpoint")
+	cmdProvidersCreateIdentityCenter.Flags().StringVar(&portalURL, "portal-url", "",
+		"(Optional) Override the AWS access portal API endpoint")
+
+	mandatoryFlag(cmdProvidersCreateIdentityCenter, "start-url")
+	mandatoryFlag(cmdProvidersCreateIdentityCenter, "region")
+
+	// OIDC
+	cmdProvidersCreateOIDC.Flags().StringVar(&issuer, "issuer", "", "OIDC issuer URL")
+	cmdProvidersCreateOIDC.Flags().StringVar(&clientID, "client-id", "", "OIDC client ID")
+	cmdProvidersCreateOIDC.Flags().StringVar(&clientSecret, "client-secret", "",
+		"(Optional) OIDC client secret, for clients which aren't public")
+	cmdProvidersCreateOIDC.Flags().StringVar(&scope, "scope", "",
+		"(Optional) Space separated scopes to request (default openid)")
+	cmdProvidersCreateOIDC.Flags().StringVar(&flow, "flow", "",
+		"(Optional) Login flow, auth-code or device (default auth-code)")
+	cmdProvidersCreateOIDC.Flags().IntVar(&port, "port", 0,
+		"(Optional) Port of the local redirect listener (default 21844)")
+	cmdProvidersCreateOIDC.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreateOIDC, "issuer")
+	mandatoryFlag(cmdProvidersCreateOIDC, "client-id")
+
+	// Exec
+	cmdProvidersCreateExec.Flags().StringVar(&command, "command", "", "Path of the helper executable")
+	cmdProvidersCreateExec.Flags().StringArrayVar(&commandArgs, "arg", nil,
+		"(Optional) Argument passed to the helper, may be repeated")
+	cmdProvidersCreateExec.Flags().IntVar(&providerDuration, "duration", 0, "(Optional) Default session duration in seconds")
+
+	mandatoryFlag(cmdProvidersCreateExec, "command")
+
 	// Build command tree
 	RootCmd.AddCommand(cmdProviders)
 	cmdProviders.AddCommand(cmdProvidersList)
 	cmdProviders.AddCommand(cmdProvidersPassword)
+	cmdProviders.AddCommand(cmdProvidersForgetDevice)
+	cmdProviders.AddCommand(cmdProvidersDelete)
 	cmdProviders.AddCommand(cmdProvidersCreate)
 	cmdProvidersCreate.AddCommand(cmdProvidersCreateOneLogin)
 	cmdProvidersCreate.AddCommand(cmdProvidersCreateOkta)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateAzureAD)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateGoogle)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateKeycloak)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateADFS)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreatePing)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateJumpCloud)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateBrowser)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateIdentityCenter)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateOIDC)
+	cmdProvidersCreate.AddCommand(cmdProvidersCreateExec)
 }
 
 var cmdProviders = &cobra.Command{
@@ -117,6 +294,68 @@ var cmdProvidersPassword = &cobra.Command{
 	},
 }
 
+var cmdProvidersForgetDevice = &cobra.Command{
+	Use:   "forget-device [provider name]",
+	Short: "Make the provider forget this device",
+	Long: `Rotate the device token which identifies this machine to an Okta provider, so that Okta
+no longer treats it as a remembered device and asks for MFA again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := args[0]
+
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+		if pType := viper.GetString(fmt.Sprintf("providers.%s.type", provider)); pType != "okta" {
+			log.Fatalf("Provider '%s' is of type '%s', only Okta providers remember devices", provider, pType)
+		}
+
+		if _, err := okta.RotateDeviceToken(provider); err != nil {
+			log.Fatalf("Could not rotate device token: %v", err)
+		}
+		log.Printf("Provider '%s' will no longer recognize this device", provider)
+	},
+}
+
+var cmdProvidersDelete = &cobra.Command{
+	Use:   "delete [provider name]",
+	Short: "Delete a provider",
+	Long: `Delete a provider from the config file. The password stored in the keychain and the tokens
+cached for the provider are removed as well, and a cached OneLogin API access token is revoked.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := args[0]
+
+		if exists := viper.Get("providers." + provider); exists == nil {
+			log.Fatalf("Provider '%s' doesn't exist", provider)
+		}
+
+		// Apps using the provider would no longer work
+		var apps []string
+		for app := range viper.GetStringMap("apps") {
+			if viper.GetString(fmt.Sprintf("apps.%s.provider", app)) == provider {
+				apps = append(apps, app)
+			}
+		}
+		if len(apps) > 0 {
+			sort.Strings(apps)
+			log.Fatalf("Provider '%s' is used by apps: %s", provider, strings.Join(apps, ", "))
+		}
+
+		forgetProvider(provider, viper.GetString(fmt.Sprintf("providers.%s.type", provider)))
+
+		// Delete provider
+		delete(viper.Get("providers").(map[string]interface{}), provider)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' deleted from config file", provider)
+	},
+}
+
 var cmdProvidersCreate = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new provider",
@@ -136,10 +375,17 @@ var cmdProvidersCreateOneLogin = &cobra.Command{
 			log.Fatalf("Provider '%s' already exists", name)
 		}
 
-		switch region {
-		case "US", "EU":
-		default:
-			log.Fatal("Region must be either US or EU")
+		// The region is ignored if an API base URL is set
+		if apiBaseURL != "" {
+			if _, err := onelogin.ParseBaseURL(apiBaseURL); err != nil {
+				log.Fatalf("Invalid value for --api-base-url: %v", err)
+			}
+		} else {
+			switch region {
+			case "US", "EU":
+			default:
+				log.Fatal("Region must be either US or EU")
+			}
 		}
 
 		conf := map[string]string{
@@ -150,6 +396,9 @@ var cmdProvidersCreateOneLogin = &cobra.Command{
 			"username":      username,
 			"region":        region,
 		}
+		if apiBaseURL != "" {
+			conf["api-base-url"] = apiBaseURL
+		}
 		if providerDuration != 0 {
 			// Duration specified - validate value
 			if providerDuration < 3600 || providerDuration > 43200 {
@@ -186,6 +435,31 @@ var cmdProvidersCreateOkta = &cobra.Command{
 			"type":     "okta",
 			"username": username,
 		}
+		if mfaFactor != "" {
+			conf["mfa-factor"] = mfaFactor
+		}
+		if mfaPushTimeout < 0 || mfaInterval < 0 {
+			log.Fatal("MFA push timeout and interval must not be negative")
+		}
+		if mfaPushTimeout != 0 {
+			conf["mfa-push-timeout"] = strconv.Itoa(mfaPushTimeout)
+		}
+		if mfaInterval != 0 {
+			conf["mfa-interval"] = strconv.Itoa(mfaInterval)
+		}
+		switch authFlow {
+		case "":
+		case okta.AuthFlowClassic, okta.AuthFlowIDX, okta.AuthFlowDevice:
+			conf["auth-flow"] = authFlow
+		default:
+			log.Fatalf("Invalid auth flow '%s'. Valid values: %s, %s, %s", authFlow, okta.AuthFlowClassic, okta.AuthFlowIDX, okta.AuthFlowDevice)
+		}
+		if authFlow == okta.AuthFlowDevice && clientID == "" {
+			log.Fatal("The device auth flow requires a client ID")
+		}
+		if clientID != "" {
+			conf["client-id"] = clientID
+		}
 		if providerDuration != 0 {
 			// Duration specified - validate value
 			if providerDuration < 3600 || providerDuration > 43200 {
@@ -203,3 +477,445 @@ var cmdProvidersCreateOkta = &cobra.Command{
 		log.Printf("Provider '%s' saved to config file", name)
 	},
 }
+
+var cmdProvidersCreateAzureAD = &cobra.Command{
+	Use:   "azuread [provider name]",
+	Short: "Create a new Entra ID (Azure AD) provider",
+	Long:  "Save a new Microsoft Entra ID (Azure AD) provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		switch mfaMethod {
+		case "", "PhoneAppNotification", "PhoneAppOTP", "OneWaySMS":
+		default:
+			log.Fatal("MFA method must be one of PhoneAppNotification, PhoneAppOTP or OneWaySMS")
+		}
+
+		conf := map[string]string{
+			"tenant-id": tenantID,
+			"type":      "azuread",
+			"username":  username,
+		}
+		if loginURL != "" {
+			conf["login-url"] = loginURL
+		}
+		if mfaMethod != "" {
+			conf["mfa-method"] = mfaMethod
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateGoogle = &cobra.Command{
+	Use:   "google [provider name]",
+	Short: "Create a new Google Workspace provider",
+	Long:  "Save a new Google Workspace provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]string{
+			"idp-id":   idpID,
+			"type":     "google",
+			"username": username,
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateKeycloak = &cobra.Command{
+	Use:   "keycloak [provider name]",
+	Short: "Create a new Keycloak provider",
+	Long:  "Save a new Keycloak provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]string{
+			"base-url": baseURL,
+			"realm":    realm,
+			"type":     "keycloak",
+			"username": username,
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateADFS = &cobra.Command{
+	Use:   "adfs [provider name]",
+	Short: "Create a new ADFS provider",
+	Long:  "Save a new Active Directory Federation Services provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]string{
+			"base-url": baseURL,
+			"type":     "adfs",
+			"username": username,
+		}
+		if mfaMethod != "" {
+			conf["mfa-method"] = mfaMethod
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreatePing = &cobra.Command{
+	Use:   "ping [provider name]",
+	Short: "Create a new PingFederate provider",
+	Long:  "Save a new PingFederate provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]string{
+			"base-url": baseURL,
+			"type":     "ping",
+			"username": username,
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateJumpCloud = &cobra.Command{
+	Use:   "jumpcloud [provider name]",
+	Short: "Create a new JumpCloud provider",
+	Long:  "Save a new JumpCloud provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		if mfaMethod != "" && mfaMethod != "totp" && mfaMethod != "push" {
+			log.Fatalf("Invalid MFA method '%s'. Valid values: totp, push", mfaMethod)
+		}
+
+		conf := map[string]string{
+			"type":     "jumpcloud",
+			"username": username,
+		}
+		if mfaMethod != "" {
+			conf["mfa-method"] = mfaMethod
+		}
+		if baseURL != "" {
+			conf["base-url"] = baseURL
+		}
+		if ssoURL != "" {
+			conf["sso-url"] = ssoURL
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateBrowser = &cobra.Command{
+	Use:   "browser [provider name]",
+	Short: "Create a new browser provider",
+	Long: `Save a new browser provider into the config file.
+
+The browser provider lets you log in to any SAML IdP using a browser. The IdP has to send the
+SAML response to the local ACS listener, http://127.0.0.1:<port>/saml.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]string{
+			"type": "browser",
+		}
+		if port != 0 {
+			if port < 1 || port > 65535 {
+				log.Fatal("Invalid port Specified. Valid values: 1 - 65535")
+			}
+			conf["port"] = strconv.Itoa(port)
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateIdentityCenter = &cobra.Command{
+	Use:   "identity-center [provider name]",
+	Short: "Create a new IAM Identity Center provider",
+	Long:  "Save a new AWS IAM Identity Center provider into the config file.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]string{
+			"region":    ssoRegion,
+			"start-url": startURL,
+			"type":      "identity-center",
+		}
+		if oidcURL != "" {
+			conf["oidc-url"] = oidcURL
+		}
+		if portalURL != "" {
+			conf["portal-url"] = portalURL
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateOIDC = &cobra.Command{
+	Use:   "oidc [provider name]",
+	Short: "Create a new OIDC provider",
+	Long: `Save a new OpenID Connect provider into the config file.
+
+The ID token returned by the issuer is exchanged for AWS credentials using
+AssumeRoleWithWebIdentity.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		if flow != "" && flow != "auth-code" && flow != "device" {
+			log.Fatalf("Invalid flow '%s'. Valid values: auth-code, device", flow)
+		}
+
+		conf := map[string]string{
+			"client-id": clientID,
+			"issuer":    issuer,
+			"type":      "oidc",
+		}
+		if clientSecret != "" {
+			conf["client-secret"] = clientSecret
+		}
+		if scope != "" {
+			conf["scope"] = scope
+		}
+		if flow != "" {
+			conf["flow"] = flow
+		}
+		if port != 0 {
+			if port < 1 || port > 65535 {
+				log.Fatal("Invalid port Specified. Valid values: 1 - 65535")
+			}
+			conf["port"] = strconv.Itoa(port)
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+var cmdProvidersCreateExec = &cobra.Command{
+	Use:   "exec [provider name]",
+	Short: "Create a new provider backed by an external helper",
+	Long: `Save a new provider which runs an external helper into the config file.
+
+The helper receives a JSON request on stdin and returns a SAML assertion or credentials as JSON on
+stdout. See the README for a description of the protocol.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		// Verify provider doesn't exist
+		if exists := viper.Get("providers." + name); exists != nil {
+			log.Fatalf("Provider '%s' already exists", name)
+		}
+
+		conf := map[string]interface{}{
+			"command": command,
+			"type":    "exec",
+		}
+		if len(commandArgs) > 0 {
+			conf["args"] = commandArgs
+		}
+		if providerDuration != 0 {
+			// Duration specified - validate value
+			if providerDuration < 3600 || providerDuration > 43200 {
+				log.Fatal("Invalid duration Specified. Valid values: 3600 - 43200")
+			}
+			conf["duration"] = strconv.Itoa(providerDuration)
+		}
+		viper.Set(fmt.Sprintf("providers.%s", name), conf)
+
+		// Write config to file
+		err := viper.WriteConfig()
+		if err != nil {
+			log.Fatalf("Error writing config: %v", err)
+		}
+		log.Printf("Provider '%s' saved to config file", name)
+	},
+}
+
+// forgetProvider removes the password and the tokens stored for a provider which is about to be
+// deleted. Failures are logged, since they shouldn't prevent the deletion.
+func forgetProvider(provider, pType string) {
+	var err error
+	switch pType {
+	case "okta":
+		err = okta.DeleteCache(provider)
+	case "oidc":
+		err = oidc.DeleteCache(provider)
+	case "onelogin":
+		err = onelogin.RevokeToken(provider)
+	}
+	if err != nil {
+		log.Warnf("Could not remove cached tokens: %v", err)
+	}
+
+	if err := keychain.Delete(provider); err != nil {
+		log.Warnf("Could not remove password from keychain: %v", err)
+	}
+}
@@ -22,6 +22,7 @@ type OneLoginProviderConfig struct {
 	Type         string
 	Username     string
 	Region       string
+	APIBaseURL   string
 }
 
 // GetOneLoginProvider returns a OneLoginProviderConfig struct containing the configuration for
@@ -33,12 +34,14 @@ func GetOneLoginProvider(p string) (*OneLoginProviderConfig, error) {
 	subdomain := viper.GetString(fmt.Sprintf("providers.%s.subdomain", p))
 	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
 	region := viper.GetString(fmt.Sprintf("providers.%s.region", p))
+	apiBaseURL := viper.GetString(fmt.Sprintf("providers.%s.api-base-url", p))
 	log.WithFields(log.Fields{
 		"clientSecret": gog.If(log.GetLevel() == log.TraceLevel, clientSecret, "<redacted>"),
 		"clientID":     clientID,
 		"subdomain":    subdomain,
 		"username":     username,
 		"region":       region,
+		"apiBaseURL":   apiBaseURL,
 	}).Debug("Read OneLogin provider config")
 
 	if clientSecret == "" {
@@ -61,6 +64,7 @@ func GetOneLoginProvider(p string) (*OneLoginProviderConfig, error) {
 		Subdomain:    subdomain,
 		Username:     username,
 		Region:       region,
+		APIBaseURL:   apiBaseURL,
 	}
 
 	return &c, nil
@@ -92,20 +96,42 @@ func GetOneLoginApp(app string) (*OneLoginAppConfig, error) {
 
 // OktaProviderConfig represents an Okta provider configuration.
 type OktaProviderConfig struct {
-	BaseURL  string
-	Username string
+	BaseURL        string
+	Username       string
+	MFAFactor      string
+	MFAPushTimeout int
+	MFAInterval    int
+	AuthFlow       string
+	ClientID       string
 }
 
 // GetOktaProvider returns a OktaProviderConfig struct containing the configuration for provider p.
 func GetOktaProvider(p string) (*OktaProviderConfig, error) {
 	baseURL := viper.GetString(fmt.Sprintf("providers.%s.base-url", p))
 	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+	mfaFactor := viper.GetString(fmt.Sprintf("providers.%s.mfa-factor", p))
+	mfaPushTimeout := viper.GetInt(fmt.Sprintf("providers.%s.mfa-push-timeout", p))
+	mfaInterval := viper.GetInt(fmt.Sprintf("providers.%s.mfa-interval", p))
+	authFlow := viper.GetString(fmt.Sprintf("providers.%s.auth-flow", p))
+	clientID := viper.GetString(fmt.Sprintf("providers.%s.client-id", p))
 
 	if baseURL == "" {
 		return nil, errors.New("base-url config value must bet set")
 	}
 
-	return &OktaProviderConfig{BaseURL: baseURL, Username: username}, nil
+	if authFlow == "device" && clientID == "" {
+		return nil, errors.New("client-id config value must be set for the device auth flow")
+	}
+
+	return &OktaProviderConfig{
+		BaseURL:        baseURL,
+		Username:       username,
+		MFAFactor:      mfaFactor,
+		MFAPushTimeout: mfaPushTimeout,
+		MFAInterval:    mfaInterval,
+		AuthFlow:       authFlow,
+		ClientID:       clientID,
+	}, nil
 }
 
 // OktaAppConfig represents an Okta app configuration.
@@ -134,3 +160,486 @@ func GetOktaApp(app string) (*OktaAppConfig, error) {
 		URL:      url,
 	}, nil
 }
+
+// AzureADProviderConfig represents a Microsoft Entra ID (Azure AD) provider configuration.
+type AzureADProviderConfig struct {
+	TenantID  string
+	Username  string
+	LoginURL  string
+	MFAMethod string
+}
+
+// GetAzureADProvider returns an AzureADProviderConfig struct containing the configuration for
+// provider p.
+func GetAzureADProvider(p string) (*AzureADProviderConfig, error) {
+	tenantID := viper.GetString(fmt.Sprintf("providers.%s.tenant-id", p))
+	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+	loginURL := viper.GetString(fmt.Sprintf("providers.%s.login-url", p))
+	mfaMethod := viper.GetString(fmt.Sprintf("providers.%s.mfa-method", p))
+
+	if tenantID == "" {
+		return nil, errors.New("tenant-id config value must be set")
+	}
+
+	return &AzureADProviderConfig{
+		TenantID:  tenantID,
+		Username:  username,
+		LoginURL:  loginURL,
+		MFAMethod: mfaMethod,
+	}, nil
+}
+
+// AzureADAppConfig represents a Microsoft Entra ID (Azure AD) app configuration.
+type AzureADAppConfig struct {
+	Provider string
+	AppIDURI string
+}
+
+// GetAzureADApp returns an AzureADAppConfig struct containing the configuration for app.
+func GetAzureADApp(app string) (*AzureADAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	appIDURI := config["app-id-uri"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if appIDURI == "" {
+		return nil, errors.New("app-id-uri config value must be set")
+	}
+
+	return &AzureADAppConfig{
+		Provider: provider,
+		AppIDURI: appIDURI,
+	}, nil
+}
+
+// GoogleProviderConfig represents a Google Workspace provider configuration.
+type GoogleProviderConfig struct {
+	IdPID    string
+	Username string
+	BaseURL  string
+}
+
+// GetGoogleProvider returns a GoogleProviderConfig struct containing the configuration for
+// provider p.
+func GetGoogleProvider(p string) (*GoogleProviderConfig, error) {
+	idpID := viper.GetString(fmt.Sprintf("providers.%s.idp-id", p))
+	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+	baseURL := viper.GetString(fmt.Sprintf("providers.%s.base-url", p))
+
+	if idpID == "" {
+		return nil, errors.New("idp-id config value must be set")
+	}
+
+	return &GoogleProviderConfig{IdPID: idpID, Username: username, BaseURL: baseURL}, nil
+}
+
+// GoogleAppConfig represents a Google Workspace app configuration.
+type GoogleAppConfig struct {
+	Provider string
+	AppID    string
+}
+
+// GetGoogleApp returns a GoogleAppConfig struct containing the configuration for app.
+func GetGoogleApp(app string) (*GoogleAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	appID := config["app-id"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if appID == "" {
+		return nil, errors.New("app-id config value must be set")
+	}
+
+	return &GoogleAppConfig{
+		Provider: provider,
+		AppID:    appID,
+	}, nil
+}
+
+// KeycloakProviderConfig represents a Keycloak provider configuration.
+type KeycloakProviderConfig struct {
+	BaseURL  string
+	Realm    string
+	Username string
+}
+
+// GetKeycloakProvider returns a KeycloakProviderConfig struct containing the configuration for
+// provider p.
+func GetKeycloakProvider(p string) (*KeycloakProviderConfig, error) {
+	baseURL := viper.GetString(fmt.Sprintf("providers.%s.base-url", p))
+	realm := viper.GetString(fmt.Sprintf("providers.%s.realm", p))
+	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+
+	if baseURL == "" {
+		return nil, errors.New("base-url config value must be set")
+	}
+	if realm == "" {
+		return nil, errors.New("realm config value must be set")
+	}
+
+	return &KeycloakProviderConfig{BaseURL: baseURL, Realm: realm, Username: username}, nil
+}
+
+// KeycloakAppConfig represents a Keycloak app configuration.
+type KeycloakAppConfig struct {
+	Provider string
+	ClientID string
+}
+
+// GetKeycloakApp returns a KeycloakAppConfig struct containing the configuration for app.
+func GetKeycloakApp(app string) (*KeycloakAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	clientID := config["client-id"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if clientID == "" {
+		return nil, errors.New("client-id config value must be set")
+	}
+
+	return &KeycloakAppConfig{
+		Provider: provider,
+		ClientID: clientID,
+	}, nil
+}
+
+// ADFSProviderConfig represents an ADFS provider configuration.
+type ADFSProviderConfig struct {
+	BaseURL   string
+	Username  string
+	MFAMethod string
+}
+
+// GetADFSProvider returns an ADFSProviderConfig struct containing the configuration for provider p.
+func GetADFSProvider(p string) (*ADFSProviderConfig, error) {
+	baseURL := viper.GetString(fmt.Sprintf("providers.%s.base-url", p))
+	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+	mfaMethod := viper.GetString(fmt.Sprintf("providers.%s.mfa-method", p))
+
+	if baseURL == "" {
+		return nil, errors.New("base-url config value must be set")
+	}
+
+	return &ADFSProviderConfig{BaseURL: baseURL, Username: username, MFAMethod: mfaMethod}, nil
+}
+
+// ADFSAppConfig represents an ADFS app configuration.
+type ADFSAppConfig struct {
+	Provider     string
+	RelyingParty string
+}
+
+// GetADFSApp returns an ADFSAppConfig struct containing the configuration for app.
+func GetADFSApp(app string) (*ADFSAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	relyingParty := config["relying-party"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	return &ADFSAppConfig{
+		Provider:     provider,
+		RelyingParty: relyingParty,
+	}, nil
+}
+
+// PingProviderConfig represents a PingFederate provider configuration.
+type PingProviderConfig struct {
+	BaseURL  string
+	Username string
+}
+
+// GetPingProvider returns a PingProviderConfig struct containing the configuration for provider p.
+func GetPingProvider(p string) (*PingProviderConfig, error) {
+	baseURL := viper.GetString(fmt.Sprintf("providers.%s.base-url", p))
+	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+
+	if baseURL == "" {
+		return nil, errors.New("base-url config value must be set")
+	}
+
+	return &PingProviderConfig{BaseURL: baseURL, Username: username}, nil
+}
+
+// PingAppConfig represents a PingFederate app configuration.
+type PingAppConfig struct {
+	Provider    string
+	PartnerSpID string
+}
+
+// GetPingApp returns a PingAppConfig struct containing the configuration for app.
+func GetPingApp(app string) (*PingAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	partnerSpID := config["partner-sp-id"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	return &PingAppConfig{
+		Provider:    provider,
+		PartnerSpID: partnerSpID,
+	}, nil
+}
+
+// JumpCloudProviderConfig represents a JumpCloud provider configuration.
+type JumpCloudProviderConfig struct {
+	Username  string
+	MFAMethod string
+	BaseURL   string
+	SSOURL    string
+}
+
+// GetJumpCloudProvider returns a JumpCloudProviderConfig struct containing the configuration for
+// provider p.
+func GetJumpCloudProvider(p string) (*JumpCloudProviderConfig, error) {
+	username := viper.GetString(fmt.Sprintf("providers.%s.username", p))
+	mfaMethod := viper.GetString(fmt.Sprintf("providers.%s.mfa-method", p))
+	baseURL := viper.GetString(fmt.Sprintf("providers.%s.base-url", p))
+	ssoURL := viper.GetString(fmt.Sprintf("providers.%s.sso-url", p))
+
+	return &JumpCloudProviderConfig{Username: username, MFAMethod: mfaMethod, BaseURL: baseURL, SSOURL: ssoURL}, nil
+}
+
+// JumpCloudAppConfig represents a JumpCloud app configuration.
+type JumpCloudAppConfig struct {
+	Provider string
+	AppSlug  string
+}
+
+// GetJumpCloudApp returns a JumpCloudAppConfig struct containing the configuration for app.
+func GetJumpCloudApp(app string) (*JumpCloudAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	appSlug := config["app-slug"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if appSlug == "" {
+		return nil, errors.New("app-slug config value must be set")
+	}
+
+	return &JumpCloudAppConfig{
+		Provider: provider,
+		AppSlug:  appSlug,
+	}, nil
+}
+
+// BrowserProviderConfig represents a browser provider configuration.
+type BrowserProviderConfig struct {
+	Port int
+}
+
+// GetBrowserProvider returns a BrowserProviderConfig struct containing the configuration for
+// provider p.
+func GetBrowserProvider(p string) (*BrowserProviderConfig, error) {
+	port := viper.GetInt(fmt.Sprintf("providers.%s.port", p))
+
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", port)
+	}
+
+	return &BrowserProviderConfig{Port: port}, nil
+}
+
+// BrowserAppConfig represents a browser app configuration.
+type BrowserAppConfig struct {
+	Provider string
+	URL      string
+}
+
+// GetBrowserApp returns a BrowserAppConfig struct containing the configuration for app.
+func GetBrowserApp(app string) (*BrowserAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	url := config["url"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if url == "" {
+		return nil, errors.New("url config value must be set")
+	}
+
+	return &BrowserAppConfig{
+		Provider: provider,
+		URL:      url,
+	}, nil
+}
+
+// IdentityCenterProviderConfig represents an AWS IAM Identity Center provider configuration.
+type IdentityCenterProviderConfig struct {
+	StartURL  string
+	Region    string
+	OIDCURL   string
+	PortalURL string
+}
+
+// GetIdentityCenterProvider returns an IdentityCenterProviderConfig struct containing the
+// configuration for provider p.
+func GetIdentityCenterProvider(p string) (*IdentityCenterProviderConfig, error) {
+	startURL := viper.GetString(fmt.Sprintf("providers.%s.start-url", p))
+	region := viper.GetString(fmt.Sprintf("providers.%s.region", p))
+	oidcURL := viper.GetString(fmt.Sprintf("providers.%s.oidc-url", p))
+	portalURL := viper.GetString(fmt.Sprintf("providers.%s.portal-url", p))
+
+	if startURL == "" {
+		return nil, errors.New("start-url config value must be set")
+	}
+
+	if region == "" {
+		return nil, errors.New("region config value must be set")
+	}
+
+	return &IdentityCenterProviderConfig{
+		StartURL:  startURL,
+		Region:    region,
+		OIDCURL:   oidcURL,
+		PortalURL: portalURL,
+	}, nil
+}
+
+// IdentityCenterAppConfig represents an AWS IAM Identity Center app configuration.
+type IdentityCenterAppConfig struct {
+	Provider  string
+	AccountID string
+	RoleName  string
+}
+
+// GetIdentityCenterApp returns an IdentityCenterAppConfig struct containing the configuration for
+// app.
+func GetIdentityCenterApp(app string) (*IdentityCenterAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	accountID := config["account-id"]
+	roleName := config["role-name"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if accountID == "" {
+		return nil, errors.New("account-id config value must be set")
+	}
+
+	if roleName == "" {
+		return nil, errors.New("role-name config value must be set")
+	}
+
+	return &IdentityCenterAppConfig{
+		Provider:  provider,
+		AccountID: accountID,
+		RoleName:  roleName,
+	}, nil
+}
+
+// OIDCProviderConfig represents an OpenID Connect provider configuration.
+type OIDCProviderConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+	Flow         string
+	Port         int
+}
+
+// GetOIDCProvider returns an OIDCProviderConfig struct containing the configuration for provider
+// p.
+func GetOIDCProvider(p string) (*OIDCProviderConfig, error) {
+	issuer := viper.GetString(fmt.Sprintf("providers.%s.issuer", p))
+	clientID := viper.GetString(fmt.Sprintf("providers.%s.client-id", p))
+	clientSecret := viper.GetString(fmt.Sprintf("providers.%s.client-secret", p))
+	scope := viper.GetString(fmt.Sprintf("providers.%s.scope", p))
+	flow := viper.GetString(fmt.Sprintf("providers.%s.flow", p))
+	port := viper.GetInt(fmt.Sprintf("providers.%s.port", p))
+
+	if issuer == "" {
+		return nil, errors.New("issuer config value must be set")
+	}
+
+	if clientID == "" {
+		return nil, errors.New("client-id config value must be set")
+	}
+
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", port)
+	}
+
+	return &OIDCProviderConfig{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scope:        scope,
+		Flow:         flow,
+		Port:         port,
+	}, nil
+}
+
+// OIDCAppConfig represents an OpenID Connect app configuration.
+type OIDCAppConfig struct {
+	Provider string
+	RoleARN  string
+}
+
+// GetOIDCApp returns an OIDCAppConfig struct containing the configuration for app.
+func GetOIDCApp(app string) (*OIDCAppConfig, error) {
+	config := viper.GetStringMapString("apps." + app)
+
+	provider := config["provider"]
+	roleARN := config["role-arn"]
+
+	if provider == "" {
+		return nil, errors.New("provider config value must be set")
+	}
+
+	if roleARN == "" {
+		return nil, errors.New("role-arn config value must be set")
+	}
+
+	return &OIDCAppConfig{
+		Provider: provider,
+		RoleARN:  roleARN,
+	}, nil
+}
+
+// ExecProviderConfig represents the configuration of a provider backed by an external helper.
+type ExecProviderConfig struct {
+	// Command is the helper executable followed by its arguments.
+	Command []string
+}
+
+// GetExecProvider returns an ExecProviderConfig struct containing the configuration for provider
+// p.
+func GetExecProvider(p string) (*ExecProviderConfig, error) {
+	command := viper.GetString(fmt.Sprintf("providers.%s.command", p))
+	args := viper.GetStringSlice(fmt.Sprintf("providers.%s.args", p))
+
+	if command == "" {
+		return nil, errors.New("command config value must be set")
+	}
+
+	return &ExecProviderConfig{Command: append([]string{command}, args...)}, nil
+}
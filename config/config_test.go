@@ -43,6 +43,10 @@ func TestOktaConfig(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal("https://xxxxxxxx.oktapreview.com", okta.BaseURL)
 	assert.Equal("example@example.com", okta.Username)
+	assert.Equal("OKTA:push", okta.MFAFactor)
+	assert.Equal(90, okta.MFAPushTimeout)
+	assert.Equal(3, okta.MFAInterval)
+	assert.Equal("idx", okta.AuthFlow)
 
 	app, err := GetOktaApp("sample-app-2")
 	assert.Nil(err)
@@ -55,3 +59,192 @@ func TestOktaConfig(t *testing.T) {
 	assert.Nil(app)
 	assert.Errorf(err, "url config value must be set")
 }
+
+func TestAzureADConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	azuread, err := GetAzureADProvider("sample-azuread-provider")
+	assert.Nil(err)
+	assert.Equal("00000000-0000-0000-0000-000000000000", azuread.TenantID)
+	assert.Equal("example@example.com", azuread.Username)
+	assert.Equal("PhoneAppNotification", azuread.MFAMethod)
+	assert.Equal("", azuread.LoginURL)
+
+	app, err := GetAzureADApp("sample-app-3")
+	assert.Nil(err)
+	assert.Equal("https://signin.aws.amazon.com/saml#1", app.AppIDURI)
+	assert.Equal("sample-azuread-provider", app.Provider)
+
+	// okta app is missing fields
+	app, err = GetAzureADApp("sample-app-2")
+	assert.Error(err)
+	assert.Nil(app)
+}
+
+func TestGoogleConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	google, err := GetGoogleProvider("sample-google-provider")
+	assert.Nil(err)
+	assert.Equal("C00000000", google.IdPID)
+	assert.Equal("example@example.com", google.Username)
+
+	app, err := GetGoogleApp("sample-app-4")
+	assert.Nil(err)
+	assert.Equal("123456789012", app.AppID)
+	assert.Equal("sample-google-provider", app.Provider)
+
+	// okta app is missing fields
+	app, err = GetGoogleApp("sample-app-2")
+	assert.Error(err)
+	assert.Nil(app)
+}
+
+func TestKeycloakConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	keycloak, err := GetKeycloakProvider("sample-keycloak-provider")
+	assert.Nil(err)
+	assert.Equal("https://sso.example.com", keycloak.BaseURL)
+	assert.Equal("example", keycloak.Realm)
+	assert.Equal("example", keycloak.Username)
+
+	// okta provider is missing the realm
+	keycloak, err = GetKeycloakProvider("sample-okta-provider")
+	assert.Error(err)
+	assert.Nil(keycloak)
+
+	app, err := GetKeycloakApp("sample-app-5")
+	assert.Nil(err)
+	assert.Equal("aws", app.ClientID)
+	assert.Equal("sample-keycloak-provider", app.Provider)
+}
+
+func TestADFSConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	adfs, err := GetADFSProvider("sample-adfs-provider")
+	assert.Nil(err)
+	assert.Equal("https://adfs.example.com", adfs.BaseURL)
+	assert.Equal(`EXAMPLE\example`, adfs.Username)
+	assert.Equal("AzureMfaAuthentication", adfs.MFAMethod)
+
+	app, err := GetADFSApp("sample-app-6")
+	assert.Nil(err)
+	assert.Equal("", app.RelyingParty)
+	assert.Equal("sample-adfs-provider", app.Provider)
+}
+
+func TestPingConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	ping, err := GetPingProvider("sample-ping-provider")
+	assert.Nil(err)
+	assert.Equal("https://sso.example.com", ping.BaseURL)
+	assert.Equal("example", ping.Username)
+
+	app, err := GetPingApp("sample-app-7")
+	assert.Nil(err)
+	assert.Equal("urn:amazon:webservices:example", app.PartnerSpID)
+	assert.Equal("sample-ping-provider", app.Provider)
+}
+
+func TestJumpCloudConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	jc, err := GetJumpCloudProvider("sample-jumpcloud-provider")
+	assert.Nil(err)
+	assert.Equal("example@example.com", jc.Username)
+	assert.Equal("push", jc.MFAMethod)
+
+	app, err := GetJumpCloudApp("sample-app-8")
+	assert.Nil(err)
+	assert.Equal("aws", app.AppSlug)
+	assert.Equal("sample-jumpcloud-provider", app.Provider)
+}
+
+func TestBrowserConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	b, err := GetBrowserProvider("sample-browser-provider")
+	assert.Nil(err)
+	assert.Equal(8085, b.Port)
+
+	app, err := GetBrowserApp("sample-app-9")
+	assert.Nil(err)
+	assert.Equal("https://idp.example.com/sso/aws", app.URL)
+	assert.Equal("sample-browser-provider", app.Provider)
+}
+
+func TestIdentityCenterConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	ic, err := GetIdentityCenterProvider("sample-identity-center-provider")
+	assert.Nil(err)
+	assert.Equal("https://example.awsapps.com/start", ic.StartURL)
+	assert.Equal("eu-west-1", ic.Region)
+	assert.Equal("", ic.OIDCURL)
+	assert.Equal("", ic.PortalURL)
+
+	app, err := GetIdentityCenterApp("sample-app-10")
+	assert.Nil(err)
+	assert.Equal("123456789012", app.AccountID)
+	assert.Equal("AdministratorAccess", app.RoleName)
+	assert.Equal("sample-identity-center-provider", app.Provider)
+}
+
+func TestOIDCConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	o, err := GetOIDCProvider("sample-oidc-provider")
+	assert.Nil(err)
+	assert.Equal("https://idp.example.com", o.Issuer)
+	assert.Equal("clisso", o.ClientID)
+	assert.Equal("", o.ClientSecret)
+	assert.Equal("openid offline_access", o.Scope)
+	assert.Equal("device", o.Flow)
+	assert.Equal(0, o.Port)
+
+	app, err := GetOIDCApp("sample-app-11")
+	assert.Nil(err)
+	assert.Equal("arn:aws:iam::123456789012:role/OIDCRole", app.RoleARN)
+	assert.Equal("sample-oidc-provider", app.Provider)
+}
+
+func TestExecConfig(t *testing.T) {
+	assert := assert.New(t)
+	// use the sample config file
+	viper.SetConfigFile("../sample_config.yaml")
+	err := viper.ReadInConfig()
+	assert.Nil(err)
+	e, err := GetExecProvider("sample-exec-provider")
+	assert.Nil(err)
+	assert.Equal([]string{"/usr/local/bin/clisso-helper", "--profile", "corp"}, e.Command)
+}
@@ -0,0 +1,112 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/allcloud-io/clisso/aws"
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	idp.RegisterCredentials("exec", Provider{})
+}
+
+// Provider implements idp.CredentialsProvider by running an external helper. Helpers return
+// either a SAML assertion, which is exchanged for credentials like for any other provider, or
+// credentials.
+type Provider struct{}
+
+// Credentials runs the helper configured for the provider and returns credentials for the
+// requested app.
+func (Provider) Credentials(r *idp.Request) (*aws.Credentials, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting credentials from helper")
+
+	p, err := config.GetExecProvider(r.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("reading provider config: %v", err)
+	}
+
+	resp, err := run(p.Command, &Request{
+		Version:        ProtocolVersion,
+		App:            r.App,
+		AppConfig:      viper.GetStringMap("apps." + r.App),
+		Provider:       r.Provider,
+		ProviderConfig: viper.GetStringMap("providers." + r.Provider),
+		Interactive:    r.Interactive,
+		PreferredARN:   r.PreferredARN,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.SAMLAssertion != "" {
+		return idp.AssumeRole(r, resp.SAMLAssertion)
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     resp.Credentials.AccessKeyID,
+		SecretAccessKey: resp.Credentials.SecretAccessKey,
+		SessionToken:    resp.Credentials.SessionToken,
+		Expiration:      resp.Credentials.Expiration,
+	}, nil
+}
+
+// run runs the helper with the request on stdin and returns its validated response. The stderr
+// of the helper is passed through, so it can prompt the user.
+func run(command []string, req *Request) (*Response, error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("serializing helper request: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+
+	log.WithField("command", command).Debug("Running helper")
+	err = cmd.Run()
+
+	// Try to parse the response even if the helper failed, since it may contain an error message.
+	var resp Response
+	parseErr := json.Unmarshal(out.Bytes(), &resp)
+	if parseErr == nil && resp.Error != "" {
+		return nil, fmt.Errorf("helper failed: %s", resp.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running helper %s: %v", command[0], err)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("parsing helper response: %v", parseErr)
+	}
+
+	if resp.Version != ProtocolVersion {
+		return nil, fmt.Errorf("unsupported helper protocol version %d, expected %d", resp.Version, ProtocolVersion)
+	}
+	if (resp.SAMLAssertion == "") == (resp.Credentials == nil) {
+		return nil, errors.New("helper must return either a SAML assertion or credentials")
+	}
+	if c := resp.Credentials; c != nil && (c.AccessKeyID == "" || c.SecretAccessKey == "") {
+		return nil, errors.New("helper returned incomplete credentials")
+	}
+
+	return &resp, nil
+}
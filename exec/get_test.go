@@ -0,0 +1,125 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package exec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+// helper writes a shell script which saves its stdin to a file and prints output, and returns
+// the command to run it and the path of the saved request.
+func helper(t *testing.T, output string, exitCode int) ([]string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper scripts require a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	reqFile := filepath.Join(dir, "request.json")
+	script := filepath.Join(dir, "helper.sh")
+	content := "#!/bin/sh\ncat > \"$1\"\necho 'Touch your security key' >&2\ncat <<'EOF'\n" + output + "\nEOF\nexit " +
+		strconv.Itoa(exitCode) + "\n"
+	assert.Nil(t, os.WriteFile(script, []byte(content), 0700))
+
+	return []string{script, reqFile}, reqFile
+}
+
+func TestRunCredentials(t *testing.T) {
+	command, reqFile := helper(t, `{"version":1,"credentials":{"accessKeyId":"ASIA","secretAccessKey":"secret","sessionToken":"session","expiration":"2030-01-01T00:00:00Z"}}`, 0)
+
+	resp, err := run(command, &Request{
+		Version:        ProtocolVersion,
+		App:            "app",
+		AppConfig:      map[string]interface{}{"provider": "helper", "account": "dev"},
+		Provider:       "helper",
+		ProviderConfig: map[string]interface{}{"type": "exec"},
+		Interactive:    true,
+		PreferredARN:   "arn:aws:iam::123456789012:role/Admin",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "ASIA", resp.Credentials.AccessKeyID)
+	assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), resp.Credentials.Expiration)
+
+	data, err := os.ReadFile(reqFile)
+	assert.Nil(t, err)
+	var req map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &req))
+	assert.Equal(t, float64(1), req["version"])
+	assert.Equal(t, "app", req["app"])
+	assert.Equal(t, "dev", req["appConfig"].(map[string]interface{})["account"])
+	assert.Equal(t, "helper", req["provider"])
+	assert.Equal(t, true, req["interactive"])
+	assert.Equal(t, "arn:aws:iam::123456789012:role/Admin", req["preferredArn"])
+}
+
+func TestRunStderr(t *testing.T) {
+	command, _ := helper(t, `{"version":1,"samlAssertion":"abc"}`, 0)
+
+	var resp *Response
+	var err error
+	stdout, stderr := testutil.CaptureOutput(t, func() {
+		resp, err = run(command, &Request{Version: ProtocolVersion})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", resp.SAMLAssertion)
+
+	// Prompts of the helper reach the user without ending up in the response or on stdout.
+	assert.Empty(t, stdout)
+	assert.Equal(t, "Touch your security key\n", stderr)
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		output   string
+		exitCode int
+		err      string
+	}{
+		{`{"version":1,"error":"user cancelled"}`, 1, "helper failed: user cancelled"},
+		{`{"version":2,"samlAssertion":"abc"}`, 0, "unsupported helper protocol version 2, expected 1"},
+		{`{"version":1}`, 0, "helper must return either a SAML assertion or credentials"},
+		{`{"version":1,"credentials":{"accessKeyId":"ASIA"}}`, 0, "helper returned incomplete credentials"},
+		{`not json`, 0, "parsing helper response: invalid character 'o' in literal null (expecting 'u')"},
+	}
+
+	for _, test := range tests {
+		command, _ := helper(t, test.output, test.exitCode)
+		_, err := run(command, &Request{Version: ProtocolVersion})
+		assert.EqualError(t, err, test.err)
+	}
+
+	command, _ := helper(t, "", 3)
+	_, err := run(command, &Request{Version: ProtocolVersion})
+	assert.EqualError(t, err, "running helper "+command[0]+": exit status 3")
+}
+
+func TestCredentials(t *testing.T) {
+	command, _ := helper(t, `{"version":1,"credentials":{"accessKeyId":"ASIA","secretAccessKey":"secret","sessionToken":"session","expiration":"2030-01-01T00:00:00Z"}}`, 0)
+
+	viper.Set("providers.test-exec", map[string]interface{}{
+		"type":    "exec",
+		"command": command[0],
+		"args":    command[1:],
+	})
+	viper.Set("apps.test-exec-app", map[string]string{"provider": "test-exec"})
+
+	creds, err := idp.Get("exec", &idp.Request{App: "test-exec-app", Provider: "test-exec"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ASIA", creds.AccessKeyID)
+	assert.Equal(t, "session", creds.SessionToken)
+}
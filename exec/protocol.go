@@ -0,0 +1,46 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package exec
+
+import "time"
+
+// ProtocolVersion is the version of the protocol spoken with helpers. It is sent with every
+// request, and helpers must return it in their response.
+const ProtocolVersion = 1
+
+// Request is sent to the helper as JSON on stdin. Since stdin carries the request, a helper which
+// prompts the user must print the prompt to stderr, which clisso passes through, and read the answer
+// from the terminal, i.e. /dev/tty, or CONIN$ on Windows.
+type Request struct {
+	Version        int                    `json:"version"`
+	App            string                 `json:"app"`
+	AppConfig      map[string]interface{} `json:"appConfig"`
+	Provider       string                 `json:"provider"`
+	ProviderConfig map[string]interface{} `json:"providerConfig"`
+	Interactive    bool                   `json:"interactive"`
+	PreferredARN   string                 `json:"preferredArn,omitempty"`
+}
+
+// Response is returned by the helper as JSON on stdout. Exactly one of SAMLAssertion and
+// Credentials must be set, unless the helper reports an error.
+type Response struct {
+	Version int `json:"version"`
+	// SAMLAssertion is a base64 encoded SAML assertion, which clisso uses to select a role and
+	// assume it.
+	SAMLAssertion string `json:"samlAssertion,omitempty"`
+	// Credentials are AWS credentials returned as is.
+	Credentials *Credentials `json:"credentials,omitempty"`
+	// Error is a message describing why the helper failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Credentials represents AWS credentials returned by a helper.
+type Credentials struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expiration      time.Time `json:"expiration"`
+}
@@ -0,0 +1,52 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/allcloud-io/clisso/htmlform"
+)
+
+// DefaultBaseURL is the Google accounts endpoint used when a provider doesn't configure one.
+const DefaultBaseURL = "https://accounts.google.com"
+
+// Client represents a Google accounts sign-in client.
+type Client struct {
+	*htmlform.Client
+}
+
+// InitSSO starts an IdP-initiated sign-on for the SAML app spID of the Google Workspace account
+// idpID and returns the first page of the sign-in flow.
+func (c *Client) InitSSO(idpID, spID string) (*htmlform.Page, error) {
+	u := fmt.Sprintf("%s/o/saml2/initsso?%s", c.BaseURL, url.Values{
+		"idpid":      {idpID},
+		"spid":       {spID},
+		"forceauthn": {"false"},
+	}.Encode())
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	return c.Load(req)
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL string) (*Client, error) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	c, err := htmlform.NewClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{Client: c}, nil
+}
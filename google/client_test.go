@@ -0,0 +1,126 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+const emailPage = `<html><body>
+<form novalidate method="post" action="/signin/v1/lookup" id="gaia_loginform">
+	<input name="Page" type="hidden" value="PasswordSeparationSignIn">
+	<input name="gxf" type="hidden" value="gxf_token">
+	<input id="Email" name="Email" type="email" value="">
+	<input id="next" name="signIn" type="submit" value="Next">
+</form></body></html>`
+
+const passwordPage = `<html><body>
+<form novalidate method="post" action="/signin/challenge/sl/password" id="gaia_loginform">
+	<input name="gxf" type="hidden" value="gxf_token">
+	<input id="Email" name="Email" type="hidden" value="user@example.com">
+	<input id="Passwd" name="Passwd" type="password">
+	%s
+</form></body></html>`
+
+const pushPage = `<html><body>
+<div id="challenge-number">37</div>
+<form method="post" action="/signin/challenge/az/2" id="challenge">
+	<input name="challengeId" type="hidden" value="2">
+	<input name="challengeType" type="hidden" value="39">
+	<input name="TL" type="hidden" value="tl_token">
+</form></body></html>`
+
+const samlPage = `<html><body onload="document.forms[0].submit()">
+<form action="https://signin.aws.amazon.com/saml" method="post">
+	<input type="hidden" name="SAMLResponse" value="fake_assertion">
+	<input type="hidden" name="RelayState" value="">
+</form></body></html>`
+
+// getTestServer returns a stand-in for the Google accounts endpoints which walks through the
+// email page, the password page and a push challenge.
+func getTestServer(t *testing.T) *httptest.Server {
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/o/saml2/initsso", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "C0idp", r.URL.Query().Get("idpid"))
+		assert.Equal(t, "123456", r.URL.Query().Get("spid"))
+		http.Redirect(w, r, "/ServiceLogin", http.StatusFound)
+	})
+	mux.HandleFunc("/ServiceLogin", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "GAPS", Value: "session"})
+		fmt.Fprint(w, emailPage)
+	})
+	mux.HandleFunc("/signin/v1/lookup", func(w http.ResponseWriter, r *http.Request) {
+		_, err := r.Cookie("GAPS")
+		assert.Nil(t, err)
+		assert.Equal(t, "user@example.com", r.PostFormValue("Email"))
+		assert.Equal(t, "gxf_token", r.PostFormValue("gxf"))
+		fmt.Fprintf(w, passwordPage, "")
+	})
+	mux.HandleFunc("/signin/challenge/sl/password", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("Passwd") != "secret" {
+			fmt.Fprintf(w, passwordPage, `<span class="error-msg" id="errormsg_0_Passwd">Wrong password. Try again.</span>`)
+			return
+		}
+		fmt.Fprint(w, pushPage)
+	})
+	mux.HandleFunc("/signin/challenge/az/2", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "tl_token", r.PostFormValue("TL"))
+		polls++
+		if polls < 2 {
+			fmt.Fprint(w, pushPage)
+			return
+		}
+		fmt.Fprint(w, samlPage)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestLogin(t *testing.T) {
+	mfaInterval = time.Millisecond
+
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	assertion, err := login(c, &loginParams{
+		IdPID:    "C0idp",
+		AppID:    "123456",
+		Username: "user@example.com",
+		Password: "secret",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{
+		IdPID:    "C0idp",
+		AppID:    "123456",
+		Username: "user@example.com",
+		Password: "wrong",
+	})
+	assert.EqualError(t, err, "sign-in failed: Wrong password. Try again.")
+}
@@ -0,0 +1,248 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package google
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/htmlform"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/icza/gog"
+)
+
+const (
+	// MFAPushTimeout represents the number of seconds to wait for a push notification to be
+	// approved on the user's phone.
+	MFAPushTimeout = 60
+
+	// Selectors of the forms making up the Google sign-in flow.
+	loginFormSelector = "form#gaia_loginform"
+	totpFormSelector  = `form[action*="/challenge/totp/"]`
+	pushFormSelector  = `form[action*="/challenge/az/"]`
+
+	// maxPages limits the number of pages followed during a single login.
+	maxPages = 10
+)
+
+var (
+	keyChain = keychain.DefaultKeychain{}
+
+	// mfaInterval represents the interval at which we check for an approved push notification.
+	mfaInterval = 2 * time.Second
+)
+
+func init() {
+	idp.Register("google", Provider{})
+}
+
+// Provider implements idp.Provider for Google Workspace.
+type Provider struct{}
+
+// Assertion signs in to Google and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from Google")
+
+	p, err := config.GetGoogleProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetGoogleApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c, err := NewClient(p.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("initializing Google client: %v", err)
+	}
+
+	user := p.Username
+	if user == "" {
+		user, err = prompt.Ask("Google username: ", r.Interactive)
+		if err != nil {
+			return "", fmt.Errorf("reading username: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	log.WithFields(log.Fields{
+		"Username": user,
+		// print password only in Trace Log Level
+		"Password": gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"IdPID":    p.IdPID,
+		"AppID":    a.AppID,
+	}).Debug("Signing in to Google")
+
+	return login(c, &loginParams{
+		IdPID:       p.IdPID,
+		AppID:       a.AppID,
+		Username:    user,
+		Password:    string(pass),
+		Interactive: r.Interactive,
+	})
+}
+
+type loginParams struct {
+	IdPID       string
+	AppID       string
+	Username    string
+	Password    string
+	Interactive bool
+}
+
+// login walks through the pages of the Google sign-in flow until a page carrying the SAML
+// response is returned.
+func login(c *Client, p *loginParams) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	page, err := c.InitSSO(p.IdPID, p.AppID)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("starting sign-in: %v", err)
+	}
+
+	var emailSent, passwordSent bool
+	for i := 0; i < maxPages; i++ {
+		if assertion := page.SAMLResponse(); assertion != "" {
+			return assertion, nil
+		}
+
+		if page.Doc.Find(`input[name="logincaptcha"], input[name="identifier-captcha-input"]`).Length() > 0 {
+			return "", errors.New("a CAPTCHA must be solved, please sign in to Google using a browser first")
+		}
+
+		form, err := page.Form(loginFormSelector)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case form != nil && page.Doc.Find(`input[name="Passwd"][type="password"]`).Length() > 0:
+			if passwordSent {
+				return "", fmt.Errorf("sign-in failed: %s", pageError(page))
+			}
+			passwordSent = true
+			form.Values.Set("Email", p.Username)
+			form.Values.Set("Passwd", p.Password)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+		case form != nil && page.Doc.Find(`input[name="Email"]`).Length() > 0:
+			if emailSent {
+				return "", fmt.Errorf("sign-in failed: %s", pageError(page))
+			}
+			emailSent = true
+			form.Values.Set("Email", p.Username)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+		default:
+			page, err = challenge(c, page, p.Interactive)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", errors.New("no SAML response received from Google")
+}
+
+// challenge completes the 2-step verification challenge shown on the page.
+func challenge(c *Client, page *htmlform.Page, interactive bool) (*htmlform.Page, error) {
+	s := spinner.New(interactive)
+
+	form, err := page.Form(totpFormSelector)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		log.Debug("TOTP challenge")
+		otp, err := prompt.Ask("Please enter the OTP from your authenticator app: ", interactive)
+		if err != nil {
+			return nil, fmt.Errorf("reading OTP: %v", err)
+		}
+		form.Values.Set("Pin", otp)
+
+		s.Start()
+		next, err := c.Submit(form)
+		s.Stop()
+		if err != nil {
+			return nil, fmt.Errorf("verifying OTP: %v", err)
+		}
+		if f, _ := next.Form(totpFormSelector); f != nil {
+			return nil, fmt.Errorf("OTP verification failed: %s", pageError(next))
+		}
+		return next, nil
+	}
+
+	form, err = page.Form(pushFormSelector)
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		log.Debug("Push challenge")
+		msg := "Please approve the sign-in request on your phone"
+		if number := strings.TrimSpace(page.Doc.Find("#challenge-number, .challenge-number").First().Text()); number != "" {
+			msg = fmt.Sprintf("Please tap '%s' on your phone to approve the sign-in request", number)
+		}
+		fmt.Fprintln(prompt.Output(interactive), msg)
+
+		// Google answers the push form with the same challenge page until the request has
+		// been approved on the phone.
+		deadline := time.Now().Add(MFAPushTimeout * time.Second)
+		s.Start()
+		defer s.Stop()
+		for {
+			next, err := c.Submit(form)
+			if err != nil {
+				return nil, fmt.Errorf("verifying push: %v", err)
+			}
+			f, err := next.Form(pushFormSelector)
+			if err != nil {
+				return nil, err
+			}
+			if f == nil {
+				return next, nil
+			}
+			if time.Now().After(deadline) {
+				return nil, errors.New("push verification timed out")
+			}
+			form = f
+			time.Sleep(mfaInterval)
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported sign-in page '%s'", page.URL.Path)
+}
+
+// pageError returns the error message shown on a sign-in page.
+func pageError(page *htmlform.Page) string {
+	msg := strings.TrimSpace(page.Doc.Find(`.error-msg, [id^="errormsg"]`).First().Text())
+	if msg == "" {
+		return "unknown error"
+	}
+	return msg
+}
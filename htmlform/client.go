@@ -0,0 +1,93 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package htmlform
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/allcloud-io/clisso/log"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Client represents a client for identity providers whose login flow consists of HTML pages and
+// forms.
+type Client struct {
+	http.Client
+	BaseURL string
+}
+
+// Page represents a page of a login flow.
+type Page struct {
+	URL *url.URL
+	Doc *goquery.Document
+}
+
+// SAMLResponse returns the SAML assertion carried by the page, if any.
+func (p *Page) SAMLResponse() string {
+	return SAMLResponse(p.Doc)
+}
+
+// Form returns the first form of the page matching selector, or nil if there is none.
+func (p *Page) Form(selector string) (*Form, error) {
+	return Find(p.Doc, selector, p.URL)
+}
+
+// Submit submits a form and returns the resulting page.
+func (c *Client) Submit(f *Form) (*Page, error) {
+	req, err := f.Request()
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	return c.Load(req)
+}
+
+// Load executes the request and parses the returned HTML page.
+func (c *Client) Load(req *http.Request) (*Page, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("loading HTML document: %v", err)
+	}
+
+	return &Page{URL: resp.Request.URL, Doc: doc}, nil
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL string) (*Client, error) {
+	// A cookie jar is required since the client needs to follow redirects with a session cookie.
+	options := cookiejar.Options{PublicSuffixList: publicsuffix.List}
+	jar, err := cookiejar.New(&options)
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %v", err)
+	}
+
+	c := &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+	c.Jar = jar
+
+	return c, nil
+}
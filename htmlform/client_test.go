@@ -0,0 +1,58 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package htmlform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "fake_session", Path: "/"})
+		fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "fake_session" || r.PostFormValue("csrf") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, page)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL + "/")
+	assert.Nil(err)
+	assert.Equal(ts.URL, c.BaseURL)
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/start", nil)
+	assert.Nil(err)
+	p, err := c.Load(req)
+	assert.Nil(err)
+	assert.Equal("/start", p.URL.Path)
+
+	// The session cookie is sent with the form
+	f, err := p.Form("form#login")
+	assert.Nil(err)
+	p, err = c.Submit(f)
+	assert.Nil(err)
+	assert.Equal("c2FtbA==", p.SAMLResponse())
+
+	// Errors are returned for pages which aren't OK
+	c, err = NewClient(ts.URL)
+	assert.Nil(err)
+	_, err = c.Submit(f)
+	assert.EqualError(err, "403 Forbidden")
+}
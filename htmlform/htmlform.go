@@ -0,0 +1,92 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package htmlform
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Form represents an HTML form scraped from an identity provider's login pages.
+type Form struct {
+	// Action is the absolute URL the form is submitted to.
+	Action string
+	// Method is the upper case HTTP method used to submit the form.
+	Method string
+	// Values holds the values of all named inputs of the form.
+	Values url.Values
+}
+
+// Find returns the first form in doc matching selector. Relative form actions are resolved
+// against base, which should be the URL the document was loaded from. If no form matches, nil is
+// returned.
+func Find(doc *goquery.Document, selector string, base *url.URL) (*Form, error) {
+	s := doc.Find(selector).First()
+	if s.Length() == 0 {
+		return nil, nil
+	}
+	return FromSelection(s, base)
+}
+
+// FromSelection builds a Form from a selection pointing to a form element.
+func FromSelection(s *goquery.Selection, base *url.URL) (*Form, error) {
+	action, _ := s.Attr("action")
+	u, err := base.Parse(action)
+	if err != nil {
+		return nil, fmt.Errorf("parsing form action %q: %v", action, err)
+	}
+
+	method := strings.ToUpper(s.AttrOr("method", http.MethodGet))
+	if method != http.MethodPost {
+		method = http.MethodGet
+	}
+
+	values := make(url.Values)
+	s.Find("input[name]").Each(func(i int, in *goquery.Selection) {
+		name, _ := in.Attr("name")
+		switch strings.ToLower(in.AttrOr("type", "text")) {
+		case "checkbox", "radio":
+			if _, checked := in.Attr("checked"); !checked {
+				return
+			}
+		case "submit", "button", "image":
+			return
+		}
+		values.Add(name, in.AttrOr("value", ""))
+	})
+
+	return &Form{Action: u.String(), Method: method, Values: values}, nil
+}
+
+// Request builds an HTTP request which submits the form.
+func (f *Form) Request() (*http.Request, error) {
+	if f.Method == http.MethodGet {
+		u, err := url.Parse(f.Action)
+		if err != nil {
+			return nil, fmt.Errorf("parsing form action: %v", err)
+		}
+		u.RawQuery = f.Values.Encode()
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, f.Action, strings.NewReader(f.Values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// SAMLResponse returns the SAML assertion posted by the first form in doc which carries a
+// SAMLResponse input, or an empty string if there is none.
+func SAMLResponse(doc *goquery.Document) string {
+	v, _ := doc.Find("form input[name=SAMLResponse]").First().Attr("value")
+	return v
+}
@@ -0,0 +1,76 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package htmlform
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+)
+
+const page = `<html><body>
+<form id="login" action="/login?step=1" method="post">
+	<input type="text" name="username" value="">
+	<input type="password" name="password">
+	<input type="hidden" name="csrf" value="token">
+	<input type="checkbox" name="remember" value="on">
+	<input type="checkbox" name="terms" value="yes" checked>
+	<input type="submit" name="submit" value="Sign in">
+</form>
+<form id="saml" action="https://signin.aws.amazon.com/saml" method="post">
+	<input type="hidden" name="SAMLResponse" value="c2FtbA==">
+</form>
+</body></html>`
+
+func TestFind(t *testing.T) {
+	assert := assert.New(t)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	assert.Nil(err)
+	base, _ := url.Parse("https://idp.example.com/realms/test/")
+
+	f, err := Find(doc, "form#login", base)
+	assert.Nil(err)
+	assert.Equal("https://idp.example.com/login?step=1", f.Action)
+	assert.Equal(http.MethodPost, f.Method)
+	assert.Equal(url.Values{
+		"username": {""},
+		"password": {""},
+		"csrf":     {"token"},
+		"terms":    {"yes"},
+	}, f.Values)
+
+	f, err = Find(doc, "form#missing", base)
+	assert.Nil(err)
+	assert.Nil(f)
+
+	assert.Equal("c2FtbA==", SAMLResponse(doc))
+}
+
+func TestRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	f := &Form{
+		Action: "https://idp.example.com/login",
+		Method: http.MethodPost,
+		Values: url.Values{"username": {"user"}},
+	}
+	req, err := f.Request()
+	assert.Nil(err)
+	assert.Equal("application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal("username=user", string(body))
+
+	f.Method = http.MethodGet
+	req, err = f.Request()
+	assert.Nil(err)
+	assert.Equal("https://idp.example.com/login?username=user", req.URL.String())
+}
@@ -0,0 +1,212 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package identitycenter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/oauth"
+)
+
+// ErrUnauthorized is returned by GetRoleCredentials if the access token is no longer valid.
+var ErrUnauthorized = errors.New("access token is invalid or expired")
+
+// Client represents a client of the AWS SSO-OIDC and AWS SSO portal APIs.
+type Client struct {
+	http.Client
+	OIDCURL   string
+	PortalURL string
+}
+
+// RegisterClientParams represents the parameters for RegisterClient.
+type RegisterClientParams struct {
+	ClientName string `json:"clientName"`
+	ClientType string `json:"clientType"`
+}
+
+// RegisterClientResponse represents the result of a call to RegisterClient.
+type RegisterClientResponse struct {
+	ClientID              string `json:"clientId"`
+	ClientSecret          string `json:"clientSecret"`
+	ClientIDIssuedAt      int64  `json:"clientIdIssuedAt"`
+	ClientSecretExpiresAt int64  `json:"clientSecretExpiresAt"`
+}
+
+// StartDeviceAuthorizationParams represents the parameters for StartDeviceAuthorization.
+type StartDeviceAuthorizationParams struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	StartURL     string `json:"startUrl"`
+}
+
+// StartDeviceAuthorizationResponse represents the result of a call to StartDeviceAuthorization.
+type StartDeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"deviceCode"`
+	UserCode                string `json:"userCode"`
+	VerificationURI         string `json:"verificationUri"`
+	VerificationURIComplete string `json:"verificationUriComplete"`
+	ExpiresIn               int    `json:"expiresIn"`
+	Interval                int    `json:"interval"`
+}
+
+// CreateTokenParams represents the parameters for CreateToken.
+type CreateTokenParams struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	GrantType    string `json:"grantType"`
+	DeviceCode   string `json:"deviceCode"`
+}
+
+// CreateTokenResponse represents the result of a call to CreateToken.
+type CreateTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int    `json:"expiresIn"`
+}
+
+// RoleCredentials represents the credentials returned by GetRoleCredentials.
+type RoleCredentials struct {
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+	// Expiration is the expiration time in milliseconds since the epoch.
+	Expiration int64 `json:"expiration"`
+}
+
+// RegisterClient registers clisso as a public OIDC client.
+func (c *Client) RegisterClient(p *RegisterClientParams) (*RegisterClientResponse, error) {
+	var resp RegisterClientResponse
+	if err := c.doOIDC("/client/register", p, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// StartDeviceAuthorization starts the device authorization flow for the given start URL.
+func (c *Client) StartDeviceAuthorization(p *StartDeviceAuthorizationParams) (*StartDeviceAuthorizationResponse, error) {
+	var resp StartDeviceAuthorizationResponse
+	if err := c.doOIDC("/device_authorization", p, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateToken exchanges a device code for an access token. While the user hasn't completed the
+// authorization yet, an *oauth.Error with the code oauth.ErrAuthorizationPending is returned.
+func (c *Client) CreateToken(p *CreateTokenParams) (*CreateTokenResponse, error) {
+	var resp CreateTokenResponse
+	if err := c.doOIDC("/token", p, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetRoleCredentials returns credentials for the given account and role using an access token.
+func (c *Client) GetRoleCredentials(accessToken, accountID, roleName string) (*RoleCredentials, error) {
+	q := url.Values{"account_id": {accountID}, "role_name": {roleName}}
+	req, err := http.NewRequest(http.MethodGet, c.PortalURL+"/federation/credentials?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-amz-sso_bearer_token", accessToken)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var r struct {
+		RoleCredentials RoleCredentials `json:"roleCredentials"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return &r.RoleCredentials, nil
+}
+
+// doOIDC POSTs body to the given SSO-OIDC API path and decodes the JSON response into v. Errors
+// returned by the API are returned as *oauth.Error, as the SSO-OIDC API uses the OAuth 2.0 error
+// format.
+func (c *Client) doOIDC(path string, body, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("parsing body: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.OIDCURL+path, bytes.NewBuffer(b))
+	if err != nil {
+		return fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr oauth.Error
+		if err = json.NewDecoder(resp.Body).Decode(&oauthErr); err != nil || oauthErr.Code == "" {
+			return errors.New(resp.Status)
+		}
+		return &oauthErr
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return nil
+}
+
+// NewClient creates a new Client and returns a pointer to it. Empty URLs default to the public
+// endpoints of the given region.
+func NewClient(region, oidcURL, portalURL string) *Client {
+	if oidcURL == "" {
+		oidcURL = fmt.Sprintf("https://oidc.%s.amazonaws.com", region)
+	}
+	if portalURL == "" {
+		portalURL = fmt.Sprintf("https://portal.sso.%s.amazonaws.com", region)
+	}
+
+	return &Client{
+		OIDCURL:   strings.TrimSuffix(oidcURL, "/"),
+		PortalURL: strings.TrimSuffix(portalURL, "/"),
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package identitycenter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+// testServer is a stand-in for the SSO-OIDC and portal APIs.
+type testServer struct {
+	*httptest.Server
+	registrations  int
+	authorizations int
+	polls          int
+	validToken     string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ts := &testServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/client/register", func(w http.ResponseWriter, r *http.Request) {
+		var p RegisterClientParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "clisso", p.ClientName)
+		assert.Equal(t, "public", p.ClientType)
+		ts.registrations++
+		fmt.Fprintf(w, `{"clientId":"client","clientSecret":"secret","clientSecretExpiresAt":%d}`,
+			time.Now().Add(90*24*time.Hour).Unix())
+	})
+	mux.HandleFunc("/device_authorization", func(w http.ResponseWriter, r *http.Request) {
+		var p StartDeviceAuthorizationParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "client", p.ClientID)
+		assert.Equal(t, "https://example.awsapps.com/start", p.StartURL)
+		ts.authorizations++
+		fmt.Fprint(w, `{"deviceCode":"device","userCode":"ABCD-EFGH","verificationUri":"https://device.example.com",`+
+			`"verificationUriComplete":"https://device.example.com?user_code=ABCD-EFGH","expiresIn":600,"interval":0}`)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		var p CreateTokenParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, oauth.GrantTypeDeviceCode, p.GrantType)
+		assert.Equal(t, "device", p.DeviceCode)
+		ts.polls++
+		if ts.polls%2 == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"authorization_pending","error_description":"Authorization is still pending"}`)
+			return
+		}
+		ts.validToken = fmt.Sprintf("token%d", ts.polls)
+		fmt.Fprintf(w, `{"accessToken":"%s","tokenType":"Bearer","expiresIn":28800}`, ts.validToken)
+	})
+	mux.HandleFunc("/federation/credentials", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-amz-sso_bearer_token") != ts.validToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Session token not found or invalid"}`)
+			return
+		}
+		assert.Equal(t, "123456789012", r.URL.Query().Get("account_id"))
+		assert.Equal(t, "Admin", r.URL.Query().Get("role_name"))
+		fmt.Fprint(w, `{"roleCredentials":{"accessKeyId":"ASIA","secretAccessKey":"secret","sessionToken":"session","expiration":1700000000000}}`)
+	})
+
+	ts.Server = httptest.NewServer(mux)
+	return ts
+}
+
+func setup(t *testing.T) {
+	testutil.TempTokenCache(t)
+	interval, open := oauth.DefaultInterval, oauth.OpenURL
+	t.Cleanup(func() { oauth.DefaultInterval, oauth.OpenURL = interval, open })
+	oauth.DefaultInterval = time.Millisecond
+	oauth.OpenURL = func(u string) error {
+		assert.Equal(t, "https://device.example.com?user_code=ABCD-EFGH", u)
+		return nil
+	}
+}
+
+func TestGetCredentials(t *testing.T) {
+	setup(t)
+
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	c := NewClient("eu-west-1", ts.URL, ts.URL)
+	p := &params{StartURL: "https://example.awsapps.com/start", AccountID: "123456789012", RoleName: "Admin"}
+
+	creds, err := getCredentials(c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, "ASIA", creds.AccessKeyID)
+	assert.Equal(t, "secret", creds.SecretAccessKey)
+	assert.Equal(t, "session", creds.SessionToken)
+	assert.Equal(t, time.UnixMilli(1700000000000), creds.Expiration)
+	assert.Equal(t, 1, ts.authorizations)
+
+	// The cached access token is used.
+	_, err = getCredentials(c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, ts.registrations)
+	assert.Equal(t, 1, ts.authorizations)
+
+	// A rejected access token causes a new login, reusing the client registration.
+	ts.validToken = "revoked"
+	_, err = getCredentials(c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ts.authorizations)
+	assert.Equal(t, 1, ts.registrations)
+}
+
+func TestNewClient(t *testing.T) {
+	c := NewClient("eu-central-1", "", "")
+	assert.Equal(t, "https://oidc.eu-central-1.amazonaws.com", c.OIDCURL)
+	assert.Equal(t, "https://portal.sso.eu-central-1.amazonaws.com", c.PortalURL)
+
+	c = NewClient("eu-central-1", "http://localhost:8080/", "http://localhost:8081")
+	assert.Equal(t, "http://localhost:8080", c.OIDCURL)
+	assert.Equal(t, "http://localhost:8081", c.PortalURL)
+}
@@ -0,0 +1,238 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package identitycenter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allcloud-io/clisso/aws"
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/oauth"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/allcloud-io/clisso/tokencache"
+)
+
+const (
+	// ClientName is the name clisso registers itself with as an OIDC client.
+	ClientName = "clisso"
+
+	// expiryMargin is subtracted from the expiration time of cached tokens and client
+	// registrations, so they aren't used right before they expire.
+	expiryMargin = 5 * time.Minute
+)
+
+func init() {
+	idp.RegisterCredentials("identity-center", Provider{})
+}
+
+// Provider implements idp.CredentialsProvider for AWS IAM Identity Center.
+type Provider struct{}
+
+// registration is a cached OIDC client registration.
+type registration struct {
+	ClientID     string    `json:"clientId"`
+	ClientSecret string    `json:"clientSecret"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// token is a cached access token.
+type token struct {
+	AccessToken string    `json:"accessToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Credentials gets credentials for the account and role configured for the requested app. The
+// user is asked to authorize clisso in a browser unless a valid access token is cached.
+func (Provider) Credentials(r *idp.Request) (*aws.Credentials, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting credentials from IAM Identity Center")
+
+	p, err := config.GetIdentityCenterProvider(r.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetIdentityCenterApp(r.App)
+	if err != nil {
+		return nil, fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	if r.Duration != 0 {
+		log.Debug("IAM Identity Center doesn't support setting the session duration, it is configured in the permission set")
+	}
+
+	c := NewClient(p.Region, p.OIDCURL, p.PortalURL)
+
+	return getCredentials(c, &params{
+		StartURL:    p.StartURL,
+		AccountID:   a.AccountID,
+		RoleName:    a.RoleName,
+		Interactive: r.Interactive,
+	})
+}
+
+type params struct {
+	StartURL    string
+	AccountID   string
+	RoleName    string
+	Interactive bool
+}
+
+// getCredentials returns the role credentials, logging in again if the cached access token is
+// rejected.
+func getCredentials(c *Client, p *params) (*aws.Credentials, error) {
+	s := spinner.New(p.Interactive)
+
+	accessToken, err := getAccessToken(c, p)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Start()
+	rc, err := c.GetRoleCredentials(accessToken, p.AccountID, p.RoleName)
+	s.Stop()
+	if errors.Is(err, ErrUnauthorized) {
+		log.Debug("Cached access token was rejected, logging in again")
+		if err = tokencache.Delete(tokenKey(p.StartURL)); err != nil {
+			return nil, err
+		}
+		accessToken, err = getAccessToken(c, p)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Start()
+		rc, err = c.GetRoleCredentials(accessToken, p.AccountID, p.RoleName)
+		s.Stop()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting role credentials: %v", err)
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     rc.AccessKeyID,
+		SecretAccessKey: rc.SecretAccessKey,
+		SessionToken:    rc.SessionToken,
+		Expiration:      time.UnixMilli(rc.Expiration),
+	}, nil
+}
+
+// getAccessToken returns the cached access token for the start URL, or runs the device
+// authorization flow to get a new one.
+func getAccessToken(c *Client, p *params) (string, error) {
+	var t token
+	ok, err := tokencache.Load(tokenKey(p.StartURL), &t)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't read cached access token")
+	}
+	if ok && time.Now().Add(expiryMargin).Before(t.ExpiresAt) {
+		log.WithField("expiresAt", t.ExpiresAt).Debug("Using cached access token")
+		return t.AccessToken, nil
+	}
+
+	reg, err := getRegistration(c)
+	if err != nil {
+		return "", err
+	}
+
+	t, err = authorize(c, reg, p)
+	if err != nil {
+		return "", err
+	}
+
+	if err = tokencache.Save(tokenKey(p.StartURL), &t); err != nil {
+		log.WithError(err).Warn("Couldn't cache access token")
+	}
+
+	return t.AccessToken, nil
+}
+
+// getRegistration returns the cached OIDC client registration, or registers a new client.
+func getRegistration(c *Client) (*registration, error) {
+	var reg registration
+	ok, err := tokencache.Load(registrationKey(c.OIDCURL), &reg)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't read cached client registration")
+	}
+	if ok && time.Now().Add(expiryMargin).Before(reg.ExpiresAt) {
+		return &reg, nil
+	}
+
+	resp, err := c.RegisterClient(&RegisterClientParams{ClientName: ClientName, ClientType: "public"})
+	if err != nil {
+		return nil, fmt.Errorf("registering OIDC client: %v", err)
+	}
+	reg = registration{
+		ClientID:     resp.ClientID,
+		ClientSecret: resp.ClientSecret,
+		ExpiresAt:    time.Unix(resp.ClientSecretExpiresAt, 0),
+	}
+
+	if err = tokencache.Save(registrationKey(c.OIDCURL), &reg); err != nil {
+		log.WithError(err).Warn("Couldn't cache client registration")
+	}
+
+	return &reg, nil
+}
+
+// authorize runs the device authorization flow and returns the access token once the user has
+// approved the request in a browser.
+func authorize(c *Client, reg *registration, p *params) (token, error) {
+	auth, err := c.StartDeviceAuthorization(&StartDeviceAuthorizationParams{
+		ClientID:     reg.ClientID,
+		ClientSecret: reg.ClientSecret,
+		StartURL:     p.StartURL,
+	})
+	if err != nil {
+		return token{}, fmt.Errorf("starting device authorization: %v", err)
+	}
+
+	resp, err := oauth.AwaitDeviceToken(&oauth.DeviceAuthorizationResponse{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                auth.UserCode,
+		VerificationURI:         auth.VerificationURI,
+		VerificationURIComplete: auth.VerificationURIComplete,
+		ExpiresIn:               auth.ExpiresIn,
+		Interval:                auth.Interval,
+	}, p.Interactive, func(deviceCode string) (*oauth.TokenResponse, error) {
+		// SSO-OIDC takes a JSON body instead of the form used by the OAuth 2.0 token endpoint.
+		t, err := c.CreateToken(&CreateTokenParams{
+			ClientID:     reg.ClientID,
+			ClientSecret: reg.ClientSecret,
+			GrantType:    oauth.GrantTypeDeviceCode,
+			DeviceCode:   deviceCode,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &oauth.TokenResponse{AccessToken: t.AccessToken, TokenType: t.TokenType, ExpiresIn: t.ExpiresIn}, nil
+	})
+	if err != nil {
+		return token{}, err
+	}
+
+	return token{
+		AccessToken: resp.AccessToken,
+		ExpiresAt:   time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// tokenKey returns the cache key of the access token for a start URL.
+func tokenKey(startURL string) string {
+	return "identity-center-token:" + startURL
+}
+
+// registrationKey returns the cache key of the client registration for an OIDC endpoint.
+func registrationKey(oidcURL string) string {
+	return "identity-center-client:" + oidcURL
+}
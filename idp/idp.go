@@ -0,0 +1,182 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package idp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/allcloud-io/clisso/aws"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/saml"
+	"github.com/allcloud-io/clisso/spinner"
+)
+
+// ErrCanceled is returned by providers if the user interrupts clisso while it waits for the login to
+// be completed, e.g. for a push notification to be approved.
+var ErrCanceled = errors.New("canceled by the user")
+
+// Request holds everything a provider needs to know in order to authenticate the user for an app.
+type Request struct {
+	// App is the name of the clisso app credentials are requested for.
+	App string
+	// Provider is the name of the clisso provider the app is configured with.
+	Provider string
+	// PreferredARN is the role ARN configured for the app, if any.
+	PreferredARN string
+	// AWSRegion is the region used to talk to AWS STS.
+	AWSRegion string
+	// Duration is the requested session duration in seconds.
+	Duration int32
+	// Interactive is false when clisso runs as a credential_process or prints to the shell.
+	Interactive bool
+}
+
+// Provider is implemented by every identity provider supported by clisso. A Provider authenticates
+// the user and returns a base64 encoded SAML assertion for the requested app. Role selection and
+// the exchange of the assertion for AWS credentials are handled by Get.
+type Provider interface {
+	Assertion(r *Request) (string, error)
+}
+
+// CredentialsProvider is implemented by identity providers which don't return a SAML assertion
+// but AWS credentials, such as AWS IAM Identity Center.
+type CredentialsProvider interface {
+	Credentials(r *Request) (*aws.Credentials, error)
+}
+
+var (
+	mu                   sync.RWMutex
+	providers            = make(map[string]Provider)
+	credentialsProviders = make(map[string]CredentialsProvider)
+)
+
+// Register makes a provider available under the given type, which corresponds to the
+// providers.<name>.type config value. Register panics if a type is registered twice.
+func Register(pType string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if p == nil {
+		panic("idp: Register provider is nil")
+	}
+	checkDuplicate(pType)
+	providers[pType] = p
+}
+
+// RegisterCredentials makes a credentials provider available under the given type. It panics if
+// a type is registered twice.
+func RegisterCredentials(pType string, p CredentialsProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if p == nil {
+		panic("idp: RegisterCredentials provider is nil")
+	}
+	checkDuplicate(pType)
+	credentialsProviders[pType] = p
+}
+
+// checkDuplicate panics if pType is already registered. The caller must hold mu.
+func checkDuplicate(pType string) {
+	_, dup := providers[pType]
+	_, dupCreds := credentialsProviders[pType]
+	if dup || dupCreds {
+		panic("idp: Register called twice for type " + pType)
+	}
+}
+
+// Lookup returns the provider registered for the given type.
+func Lookup(pType string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	p, ok := providers[pType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported identity provider type '%s'", pType)
+	}
+	return p, nil
+}
+
+// Types returns the sorted list of registered provider types.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	types := make([]string, 0, len(providers)+len(credentialsProviders))
+	for t := range providers {
+		types = append(types, t)
+	}
+	for t := range credentialsProviders {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Get gets temporary credentials for the app in r using the provider registered for pType.
+// Credentials providers are asked for credentials directly, the SAML assertion returned by any
+// other provider is exchanged for credentials using AssumeRole.
+func Get(pType string, r *Request) (*aws.Credentials, error) {
+	log.WithFields(log.Fields{
+		"type":        pType,
+		"app":         r.App,
+		"provider":    r.Provider,
+		"pArn":        r.PreferredARN,
+		"awsRegion":   r.AWSRegion,
+		"duration":    r.Duration,
+		"interactive": r.Interactive,
+	}).Trace("Getting credentials")
+
+	mu.RLock()
+	cp, ok := credentialsProviders[pType]
+	mu.RUnlock()
+	if ok {
+		return cp.Credentials(r)
+	}
+
+	p, err := Lookup(pType)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, err := p.Assertion(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return AssumeRole(r, assertion)
+}
+
+// AssumeRole selects a role from the SAML assertion and exchanges the assertion for temporary
+// credentials. If the requested duration exceeds the maximum allowed for the role, AssumeRole
+// retries with a duration of one hour.
+func AssumeRole(r *Request, assertion string) (*aws.Credentials, error) {
+	arn, err := saml.Get(assertion, r.PreferredARN)
+	if err != nil {
+		return nil, err
+	}
+
+	s := spinner.New(r.Interactive)
+
+	s.Start()
+	creds, err := aws.AssumeSAMLRole(arn.Provider, arn.Role, assertion, r.AWSRegion, r.Duration)
+	s.Stop()
+
+	if err != nil && err.Error() == aws.ErrDurationExceeded {
+		log.Warn(aws.DurationExceededMessage)
+		s.Start()
+		creds, err = aws.AssumeSAMLRole(arn.Provider, arn.Role, assertion, r.AWSRegion, 3600)
+		s.Stop()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
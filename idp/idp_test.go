@@ -0,0 +1,79 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package idp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/allcloud-io/clisso/aws"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+type fakeProvider struct {
+	assertion string
+	err       error
+}
+
+func (f fakeProvider) Assertion(r *Request) (string, error) {
+	return f.assertion, f.err
+}
+
+type fakeCredentialsProvider struct {
+	creds *aws.Credentials
+}
+
+func (f fakeCredentialsProvider) Credentials(r *Request) (*aws.Credentials, error) {
+	return f.creds, nil
+}
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	Register("test-registry", fakeProvider{assertion: "abc"})
+
+	p, err := Lookup("test-registry")
+	assert.Nil(err)
+	a, err := p.Assertion(&Request{})
+	assert.Nil(err)
+	assert.Equal("abc", a)
+	assert.Contains(Types(), "test-registry")
+
+	_, err = Lookup("does-not-exist")
+	assert.EqualError(err, "unsupported identity provider type 'does-not-exist'")
+
+	assert.Panics(func() { Register("test-registry", fakeProvider{}) })
+}
+
+func TestGetProviderError(t *testing.T) {
+	Register("test-error", fakeProvider{err: errors.New("login failed")})
+
+	creds, err := Get("test-error", &Request{App: "app", Provider: "test-error"})
+	assert.Nil(t, creds)
+	assert.EqualError(t, err, "login failed")
+}
+
+func TestGetInvalidAssertion(t *testing.T) {
+	Register("test-invalid", fakeProvider{assertion: "not base64!"})
+
+	creds, err := Get("test-invalid", &Request{App: "app", Provider: "test-invalid"})
+	assert.Nil(t, creds)
+	assert.Error(t, err)
+}
+
+func TestGetCredentialsProvider(t *testing.T) {
+	RegisterCredentials("test-credentials", fakeCredentialsProvider{creds: &aws.Credentials{AccessKeyID: "AKIA"}})
+
+	creds, err := Get("test-credentials", &Request{App: "app", Provider: "test-credentials"})
+	assert.Nil(t, err)
+	assert.Equal(t, "AKIA", creds.AccessKeyID)
+	assert.Contains(t, Types(), "test-credentials")
+
+	assert.Panics(t, func() { Register("test-credentials", fakeProvider{}) })
+}
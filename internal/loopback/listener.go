@@ -0,0 +1,119 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+// Package loopback receives responses from the browser of the user, such as the SAML response of
+// an IdP or the authorization code of an OAuth 2.0 redirect, on the loopback interface.
+package loopback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/allcloud-io/clisso/log"
+)
+
+const successPage = `<html><head><title>Clisso</title></head><body>
+<p>Login successful. You can close this window and return to your terminal.</p>
+</body></html>`
+
+// Listener is a short-lived HTTP server on the loopback interface receiving a response from the
+// browser, such as the SAML response POSTed to the assertion consumer service (ACS) or the
+// authorization code of an OAuth 2.0 redirect.
+type Listener struct {
+	listener net.Listener
+	server   *http.Server
+	path     string
+	response string
+	handler  HandlerFunc
+	result   chan Result
+}
+
+// Result is the response received by a Listener.
+type Result struct {
+	Value string
+	Err   error
+}
+
+// HandlerFunc handles a request to a Listener and returns the response received. If the result
+// has an error, it is reported to the browser, otherwise a success page is shown. If nil is
+// returned, the handler has replied to the request itself and the listener keeps waiting, e.g.
+// for requests which don't belong to the login.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) *Result
+
+// NewListener starts a listener on 127.0.0.1 and the given port, passing requests to path to h.
+// The response describes what the listener waits for in errors. Port 0 picks a free port.
+func NewListener(port int, path, response string, h HandlerFunc) (*Listener, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("starting listener: %v", err)
+	}
+
+	l := &Listener{
+		listener: ln,
+		path:     path,
+		response: response,
+		handler:  h,
+		result:   make(chan Result, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, l.handle)
+	l.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := l.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Debug("Listener stopped")
+		}
+	}()
+
+	return l, nil
+}
+
+// URL returns the URL served by the listener.
+func (l *Listener) URL() string {
+	return fmt.Sprintf("http://%s%s", l.listener.Addr().String(), l.path)
+}
+
+// Wait blocks until a response is received or the timeout expires.
+func (l *Listener) Wait(timeout time.Duration) (string, error) {
+	select {
+	case r := <-l.result:
+		return r.Value, r.Err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("timeout waiting for the %s from the browser", l.response)
+	}
+}
+
+// Close shuts the listener down.
+func (l *Listener) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return l.server.Shutdown(ctx)
+}
+
+// handle passes a request to the handler and delivers the response received.
+func (l *Listener) handle(w http.ResponseWriter, r *http.Request) {
+	res := l.handler(w, r)
+	if res == nil {
+		return
+	}
+
+	if res.Err != nil {
+		http.Error(w, res.Err.Error(), http.StatusBadRequest)
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, successPage)
+	}
+
+	select {
+	case l.result <- *res:
+	default:
+		// A response was already received, ignore any further ones.
+	}
+}
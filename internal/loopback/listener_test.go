@@ -0,0 +1,55 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package loopback
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+func TestListener(t *testing.T) {
+	l, err := NewListener(0, "/callback", "code", func(w http.ResponseWriter, r *http.Request) *Result {
+		switch r.URL.Query().Get("code") {
+		case "":
+			// Not part of the login, keep waiting.
+			http.NotFound(w, r)
+			return nil
+		case "bad":
+			return &Result{Err: errors.New("bad code")}
+		default:
+			return &Result{Value: r.URL.Query().Get("code")}
+		}
+	})
+	assert.Nil(t, err)
+	defer l.Close()
+
+	resp, err := http.Get(l.URL())
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(l.URL() + "?code=secret")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Only the first response is delivered.
+	resp, err = http.Get(l.URL() + "?code=bad")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	code, err := l.Wait(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", code)
+
+	_, err = l.Wait(time.Millisecond)
+	assert.EqualError(t, err, "timeout waiting for the code from the browser")
+}
@@ -0,0 +1,91 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+
+// Package prompt asks the user questions and reads the answers from stdin. All answers are read
+// through a single reader, so that lines piped to stdin are not lost between prompts.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Line is a line read from stdin.
+type Line struct {
+	Text string
+	Err  error
+}
+
+// Lines are read from stdin by a single goroutine at a time, one line per request. A line that was
+// requested but not received, e.g. because the user clicked the link in an email instead of typing
+// the code, is delivered to the next prompt rather than being lost.
+var (
+	mu      sync.Mutex
+	stdin   *os.File
+	reader  *bufio.Reader
+	lines   chan Line
+	pending bool
+)
+
+// NextLine returns a channel delivering the next line read from stdin.
+func NextLine() <-chan Line {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Start over if stdin has been replaced, e.g. in tests
+	if stdin != os.Stdin {
+		stdin = os.Stdin
+		reader = bufio.NewReader(os.Stdin)
+		lines = make(chan Line, 1)
+		pending = false
+	}
+
+	if !pending && len(lines) == 0 {
+		pending = true
+		r, l := reader, lines
+		go func() {
+			text, err := r.ReadString('\n')
+			if err == io.EOF && text != "" {
+				err = nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if r == reader {
+				pending = false
+			}
+			l <- Line{Text: strings.TrimSpace(text), Err: err}
+		}()
+	}
+
+	return lines
+}
+
+// ReadLine reads a line from stdin, waiting until one is available.
+func ReadLine() (string, error) {
+	l := <-NextLine()
+	return l.Text, l.Err
+}
+
+// Ask prints the question and returns the line the user answers with.
+func Ask(question string, interactive bool) (string, error) {
+	fmt.Fprint(Output(interactive), question)
+	return ReadLine()
+}
+
+// Output returns where messages to the user are printed. When not interactive, stdout may be
+// captured, e.g. when clisso is used as a credential process, so messages are printed to stderr
+// instead.
+func Output(interactive bool) io.Writer {
+	if interactive {
+		return os.Stdout
+	}
+	return os.Stderr
+}
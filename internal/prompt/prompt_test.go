@@ -0,0 +1,39 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package prompt
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsk(t *testing.T) {
+	testutil.WithStdin(t, "first\n second \n1, 3", func() {
+		stdout, stderr := testutil.CaptureOutput(t, func() {
+			for _, expected := range []string{"first", "second", "1, 3"} {
+				answer, err := Ask("Question: ", false)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, answer)
+			}
+
+			_, err := Ask("Question: ", false)
+			assert.Equal(t, io.EOF, err)
+		})
+
+		// Prompts aren't printed to stdout when not interactive, since it may carry credentials.
+		assert.Empty(t, stdout)
+		assert.Equal(t, "Question: Question: Question: Question: ", stderr)
+	})
+}
+
+func TestOutput(t *testing.T) {
+	assert.Equal(t, os.Stdout, Output(true))
+	assert.Equal(t, os.Stderr, Output(false))
+}
@@ -0,0 +1,50 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package testutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// CaptureOutput runs f and returns what it printed to stdout and stderr.
+func CaptureOutput(t *testing.T, f func()) (string, string) {
+	t.Helper()
+
+	outR, outW, err := os.Pipe()
+	assert.Nil(t, err)
+	errR, errW, err := os.Pipe()
+	assert.Nil(t, err)
+
+	var stdout, stderr bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&stdout, outR)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&stderr, errR)
+		done <- struct{}{}
+	}()
+
+	origStdout, origStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outW, errW
+	func() {
+		defer func() { os.Stdout, os.Stderr = origStdout, origStderr }()
+		f()
+	}()
+
+	outW.Close()
+	errW.Close()
+	<-done
+	<-done
+
+	return stdout.String(), stderr.String()
+}
@@ -0,0 +1,32 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+
+// Package testutil contains helpers shared by the tests of the identity providers.
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// WithStdin replaces stdin with the given input for the duration of f.
+func WithStdin(t *testing.T, input string, f func()) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	assert.Nil(t, err)
+	_, err = w.WriteString(input)
+	assert.Nil(t, err)
+	w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	f()
+}
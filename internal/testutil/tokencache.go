@@ -0,0 +1,22 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package testutil
+
+import (
+	"testing"
+
+	"github.com/allcloud-io/clisso/tokencache"
+)
+
+// TempTokenCache makes the token cache use a temporary directory for the duration of the test.
+func TempTokenCache(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	tokenCacheDir := tokencache.Dir
+	tokencache.Dir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { tokencache.Dir = tokenCacheDir })
+}
@@ -0,0 +1,320 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package jumpcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/allcloud-io/clisso/htmlform"
+	"github.com/allcloud-io/clisso/log"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// DefaultBaseURL is the URL of the JumpCloud user portal used when a provider doesn't
+	// configure one.
+	DefaultBaseURL = "https://console.jumpcloud.com"
+	// DefaultSSOURL is the URL of the JumpCloud SSO service used when a provider doesn't configure
+	// one.
+	DefaultSSOURL = "https://sso.jumpcloud.com"
+
+	FactorTOTP = "totp"
+	FactorPush = "push"
+
+	PushStatusPending  = "pending"
+	PushStatusAccepted = "accepted"
+	PushStatusDenied   = "denied"
+	PushStatusExpired  = "expired"
+)
+
+// Client represents a JumpCloud user portal client.
+type Client struct {
+	http.Client
+	BaseURL string
+	SSOURL  string
+
+	// xsrf is the XSRF token which has to be sent along with every request to the user portal
+	// API.
+	xsrf string
+}
+
+// LoginParams represents the parameters for Login.
+type LoginParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	OTP      string `json:"otp"`
+}
+
+// Factor represents an MFA factor of a JumpCloud user.
+type Factor struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// LoginResponse represents the result of a call to Login.
+type LoginResponse struct {
+	Message string   `json:"message"`
+	Error   string   `json:"error"`
+	Factors []Factor `json:"factors"`
+}
+
+// MFARequired reports whether the login has to be completed with one of the returned factors.
+func (r *LoginResponse) MFARequired() bool {
+	return len(r.Factors) > 0
+}
+
+// PushEndpoint represents a device registered for JumpCloud Protect push notifications.
+type PushEndpoint struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PushResponse represents the state of a JumpCloud Protect push notification.
+type PushResponse struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// GetXSRFToken fetches the XSRF token of the user portal. It must be called before any other
+// method.
+func (c *Client) GetXSRFToken() error {
+	req, err := c.newRequest(http.MethodGet, c.BaseURL+"/userconsole/xsrf", nil)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		XSRF string `json:"xsrf"`
+	}
+	if _, err = c.doRequest(req, &resp); err != nil {
+		return err
+	}
+	if resp.XSRF == "" {
+		return errors.New("no XSRF token returned")
+	}
+	c.xsrf = resp.XSRF
+
+	return nil
+}
+
+// Login authenticates against the user portal. If the user has MFA enabled and no OTP is given,
+// the returned response lists the factors which can be used to complete the login. Submitting a
+// TOTP is done by calling Login again with the OTP field set.
+func (c *Client) Login(p *LoginParams) (*LoginResponse, error) {
+	req, err := c.newRequest(http.MethodPost, c.BaseURL+"/userconsole/auth", p)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp LoginResponse
+	status, err := c.doRequest(req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusUnauthorized && !resp.MFARequired() {
+		return nil, errors.New(resp.errorMessage())
+	}
+
+	return &resp, nil
+}
+
+// GetPushEndpoints returns the devices registered for JumpCloud Protect push notifications.
+func (c *Client) GetPushEndpoints() ([]PushEndpoint, error) {
+	req, err := c.newRequest(http.MethodGet, c.BaseURL+"/userconsole/api/push-endpoints", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []PushEndpoint
+	if _, err = c.doRequest(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendPush sends a push notification to the given device.
+func (c *Client) SendPush(endpointID string) (*PushResponse, error) {
+	u := fmt.Sprintf("%s/userconsole/api/push-endpoints/%s/push", c.BaseURL, endpointID)
+	req, err := c.newRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp PushResponse
+	if _, err = c.doRequest(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetPush returns the state of a push notification sent using SendPush.
+func (c *Client) GetPush(endpointID, pushID string) (*PushResponse, error) {
+	u := fmt.Sprintf("%s/userconsole/api/push-endpoints/%s/push/%s", c.BaseURL, endpointID, pushID)
+	req, err := c.newRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp PushResponse
+	if _, err = c.doRequest(req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// LoginPush completes the login using an accepted push notification.
+func (c *Client) LoginPush(pushID string) error {
+	req, err := c.newRequest(http.MethodPost, c.BaseURL+"/userconsole/auth/push",
+		map[string]string{"pushId": pushID})
+	if err != nil {
+		return err
+	}
+
+	var resp LoginResponse
+	status, err := c.doRequest(req, &resp)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusUnauthorized {
+		return errors.New(resp.errorMessage())
+	}
+
+	return nil
+}
+
+// GetSAMLResponse returns the SAML assertion for the app with the given slug. The user must be
+// logged in.
+func (c *Client) GetSAMLResponse(slug string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/saml2/%s", c.SSOURL, slug), nil)
+	if err != nil {
+		return "", fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("loading HTML document: %v", err)
+	}
+
+	saml := htmlform.SAMLResponse(doc)
+	if saml == "" {
+		return "", fmt.Errorf("no SAML response returned for app %s", slug)
+	}
+
+	return saml, nil
+}
+
+// errorMessage returns the reason a request was rejected.
+func (r *LoginResponse) errorMessage() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	if r.Error != "" {
+		return r.Error
+	}
+	return "unauthorized"
+}
+
+// newRequest constructs a request to the user portal API with an optional JSON body.
+func (c *Client) newRequest(method, url string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("parsing body: %v", err)
+		}
+		r = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.xsrf != "" {
+		req.Header.Set("X-Xsrftoken", c.xsrf)
+	}
+
+	return req, nil
+}
+
+// doRequest executes the request and decodes the JSON response into v. Unauthorized responses
+// are decoded as well since they carry the MFA factors of the user. The HTTP status code is
+// returned.
+func (c *Client) doRequest(r *http.Request, v interface{}) (int, error) {
+	resp, err := c.Do(r)
+	if err != nil {
+		return 0, fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil && err != io.EOF {
+		return resp.StatusCode, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL, ssoURL string) (*Client, error) {
+	// The session cookie set by the user portal is required to fetch the SAML assertion.
+	options := cookiejar.Options{PublicSuffixList: publicsuffix.List}
+	jar, err := cookiejar.New(&options)
+	if err != nil {
+		return nil, fmt.Errorf("creating cookie jar: %v", err)
+	}
+
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if ssoURL == "" {
+		ssoURL = DefaultSSOURL
+	}
+
+	c := &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		SSOURL:  strings.TrimSuffix(ssoURL, "/"),
+	}
+	c.Jar = jar
+
+	return c, nil
+}
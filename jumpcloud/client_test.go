@@ -0,0 +1,208 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package jumpcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+const samlPage = `<html><body onload="document.forms[0].submit()">
+<form method="POST" action="https://signin.aws.amazon.com/saml">
+	<input type="hidden" name="SAMLResponse" value="fake_assertion"/>
+	<input type="hidden" name="RelayState" value=""/>
+</form></body></html>`
+
+// getTestServer returns a stand-in for the JumpCloud user portal and SSO service. Users have both
+// TOTP and push factors available.
+func getTestServer(t *testing.T) *httptest.Server {
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/userconsole/xsrf", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"xsrf":"xsrf_token"}`)
+	})
+	mux.HandleFunc("/userconsole/auth", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "xsrf_token", r.Header.Get("X-Xsrftoken"))
+
+		var p LoginParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		if p.Email != "user@example.com" || p.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Authentication failed."}`)
+			return
+		}
+		switch p.OTP {
+		case "":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"factors":[{"type":"webauthn","status":"available"},{"type":"totp","status":"available"},{"type":"push","status":"available"}],"message":"MFA required."}`)
+		case "123456":
+			http.SetCookie(w, &http.Cookie{Name: "jcsession", Value: "session", Path: "/"})
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Invalid OTP."}`)
+		}
+	})
+	mux.HandleFunc("/userconsole/api/push-endpoints", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"device1","name":"Phone"}]`)
+	})
+	mux.HandleFunc("/userconsole/api/push-endpoints/device1/push", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		fmt.Fprint(w, `{"id":"push1","status":"pending"}`)
+	})
+	mux.HandleFunc("/userconsole/api/push-endpoints/device1/push/push1", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			fmt.Fprint(w, `{"id":"push1","status":"pending"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"push1","status":"accepted"}`)
+	})
+	mux.HandleFunc("/userconsole/auth/push", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "push1", p["pushId"])
+		http.SetCookie(w, &http.Cookie{Name: "jcsession", Value: "session", Path: "/"})
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/saml2/aws", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("jcsession"); err != nil {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, samlPage)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>Login</body></html>`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestLoginTOTP(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, ts.URL)
+	assert.Nil(t, err)
+
+	testutil.WithStdin(t, "123456\n", func() {
+		stdout, stderr := testutil.CaptureOutput(t, func() {
+			assertion, err := login(c, &loginParams{AppSlug: "aws", Username: "user@example.com", Password: "secret"})
+			assert.Nil(t, err)
+			assert.Equal(t, "fake_assertion", assertion)
+		})
+		// The OTP prompt goes to stderr when not interactive, since stdout may carry credentials.
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "Please enter the OTP")
+	})
+}
+
+func TestLoginWrongTOTP(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, ts.URL)
+	assert.Nil(t, err)
+
+	testutil.WithStdin(t, "000000\n", func() {
+		_, err := login(c, &loginParams{AppSlug: "aws", Username: "user@example.com", Password: "secret"})
+		assert.EqualError(t, err, "MFA verification failed: Invalid OTP.")
+	})
+}
+
+func TestLoginPush(t *testing.T) {
+	mfaInterval = time.Millisecond
+
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, ts.URL)
+	assert.Nil(t, err)
+
+	assertion, err := login(c, &loginParams{
+		AppSlug:   "aws",
+		Username:  "user@example.com",
+		Password:  "secret",
+		MFAMethod: FactorPush,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, ts.URL)
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{AppSlug: "aws", Username: "user@example.com", Password: "wrong"})
+	assert.EqualError(t, err, "login failed: Authentication failed.")
+}
+
+func TestGetFactor(t *testing.T) {
+	factors := []Factor{
+		{Type: "webauthn", Status: "available"},
+		{Type: FactorTOTP, Status: "available"},
+		{Type: FactorPush, Status: "available"},
+	}
+
+	f, err := getFactor(factors, FactorPush)
+	assert.Nil(t, err)
+	assert.Equal(t, FactorPush, f)
+
+	f, err = getFactor(factors, "")
+	assert.Nil(t, err)
+	assert.Equal(t, FactorTOTP, f)
+
+	_, err = getFactor([]Factor{{Type: "webauthn", Status: "available"}}, "")
+	assert.EqualError(t, err, "no supported MFA factor available")
+}
+
+func TestGetPushEndpoint(t *testing.T) {
+	endpoints := []PushEndpoint{{ID: "device1", Name: "Phone"}, {ID: "device2", Name: "Tablet"}}
+
+	e, err := getPushEndpoint(endpoints[:1], false)
+	assert.Nil(t, err)
+	assert.Equal(t, "device1", e.ID)
+
+	testutil.WithStdin(t, "3\n2\n", func() {
+		stdout, stderr := testutil.CaptureOutput(t, func() {
+			e, err = getPushEndpoint(endpoints, false)
+		})
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "Invalid value 3")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "device2", e.ID)
+
+	_, err = getPushEndpoint(nil, false)
+	assert.EqualError(t, err, "no device registered for JumpCloud Protect")
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	c, err := NewClient("", "")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultBaseURL, c.BaseURL)
+	assert.Equal(t, DefaultSSOURL, c.SSOURL)
+
+	c, err = NewClient("https://console.example.com/", "https://sso.example.com/")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://console.example.com", c.BaseURL)
+	assert.Equal(t, "https://sso.example.com", c.SSOURL)
+}
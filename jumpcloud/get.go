@@ -0,0 +1,284 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package jumpcloud
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/icza/gog"
+)
+
+const (
+	// MFAPushTimeout is the time to wait for a JumpCloud Protect push to be accepted.
+	MFAPushTimeout = 60 * time.Second
+)
+
+var (
+	keyChain = keychain.DefaultKeychain{}
+
+	// mfaInterval is the time to wait between polling the status of a push notification.
+	mfaInterval = 2 * time.Second
+)
+
+func init() {
+	idp.Register("jumpcloud", Provider{})
+}
+
+// Provider implements idp.Provider for JumpCloud.
+type Provider struct{}
+
+// Assertion logs in to the JumpCloud user portal and returns a SAML assertion for the requested
+// app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from JumpCloud")
+
+	p, err := config.GetJumpCloudProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetJumpCloudApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c, err := NewClient(p.BaseURL, p.SSOURL)
+	if err != nil {
+		return "", fmt.Errorf("initializing JumpCloud client: %v", err)
+	}
+
+	user := p.Username
+	if user == "" {
+		user, err = prompt.Ask("JumpCloud email: ", r.Interactive)
+		if err != nil {
+			return "", fmt.Errorf("reading email: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	log.WithFields(log.Fields{
+		"Username": user,
+		// print password only in Trace Log Level
+		"Password":  gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"AppSlug":   a.AppSlug,
+		"MFAMethod": p.MFAMethod,
+	}).Debug("Logging in to JumpCloud")
+
+	return login(c, &loginParams{
+		AppSlug:     a.AppSlug,
+		Username:    user,
+		Password:    string(pass),
+		MFAMethod:   p.MFAMethod,
+		Interactive: r.Interactive,
+	})
+}
+
+type loginParams struct {
+	AppSlug     string
+	Username    string
+	Password    string
+	MFAMethod   string
+	Interactive bool
+}
+
+// login authenticates against the JumpCloud user portal, completes MFA if required and returns
+// the SAML assertion for the app.
+func login(c *Client, p *loginParams) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	err := c.GetXSRFToken()
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("getting XSRF token: %v", err)
+	}
+
+	creds := &LoginParams{Email: p.Username, Password: p.Password}
+
+	s.Start()
+	resp, err := c.Login(creds)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("login failed: %v", err)
+	}
+
+	if resp.MFARequired() {
+		factor, err := getFactor(resp.Factors, p.MFAMethod)
+		if err != nil {
+			return "", err
+		}
+		log.WithField("factor", factor).Debug("MFA required")
+
+		switch factor {
+		case FactorTOTP:
+			creds.OTP, err = prompt.Ask("Please enter the OTP from your MFA device: ", p.Interactive)
+			if err != nil {
+				return "", fmt.Errorf("reading OTP: %v", err)
+			}
+
+			s.Start()
+			resp, err = c.Login(creds)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("MFA verification failed: %v", err)
+			}
+			if resp.MFARequired() {
+				return "", fmt.Errorf("MFA verification failed: %s", resp.errorMessage())
+			}
+		case FactorPush:
+			if err = verifyPush(c, p.Interactive); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	s.Start()
+	assertion, err := c.GetSAMLResponse(p.AppSlug)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("getting SAML assertion: %v", err)
+	}
+
+	return assertion, nil
+}
+
+// verifyPush sends a JumpCloud Protect push notification to a registered device and completes the
+// login once it is accepted.
+func verifyPush(c *Client, interactive bool) error {
+	endpoints, err := c.GetPushEndpoints()
+	if err != nil {
+		return fmt.Errorf("getting push devices: %v", err)
+	}
+	endpoint, err := getPushEndpoint(endpoints, interactive)
+	if err != nil {
+		return err
+	}
+
+	push, err := c.SendPush(endpoint.ID)
+	if err != nil {
+		return fmt.Errorf("sending push notification: %v", err)
+	}
+
+	s := spinner.New(interactive)
+	msg := fmt.Sprintf("Please accept the JumpCloud Protect notification on %s", endpoint.Name)
+	fmt.Fprintln(prompt.Output(interactive), msg)
+	s.Start()
+	defer s.Stop()
+
+	timeout := time.Now().Add(MFAPushTimeout)
+	for push.Status == PushStatusPending {
+		if time.Now().After(timeout) {
+			return errors.New("timeout waiting for push notification to be accepted")
+		}
+		time.Sleep(mfaInterval)
+
+		push, err = c.GetPush(endpoint.ID, push.ID)
+		if err != nil {
+			return fmt.Errorf("polling push notification: %v", err)
+		}
+		log.WithField("status", push.Status).Trace("Push notification status")
+	}
+
+	switch push.Status {
+	case PushStatusAccepted:
+		if err = c.LoginPush(push.ID); err != nil {
+			return fmt.Errorf("MFA verification failed: %v", err)
+		}
+		return nil
+	case PushStatusDenied:
+		return errors.New("push notification was denied")
+	case PushStatusExpired:
+		return errors.New("push notification expired")
+	default:
+		return fmt.Errorf("unexpected push notification status '%s'", push.Status)
+	}
+}
+
+// getPushEndpoint returns the device to send the push notification to. If several devices are
+// registered, the user is asked to choose one.
+func getPushEndpoint(endpoints []PushEndpoint, interactive bool) (*PushEndpoint, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no device registered for JumpCloud Protect")
+	}
+
+	if len(endpoints) == 1 {
+		return &endpoints[0], nil
+	}
+
+	out := prompt.Output(interactive)
+	var selection int
+	for {
+		for i, e := range endpoints {
+			fmt.Fprintf(out, "%d. %s\n", i+1, e.Name)
+		}
+
+		input, err := prompt.Ask(fmt.Sprintf("Please choose a device to send the push notification to (1-%d): ", len(endpoints)), interactive)
+		if err != nil {
+			return nil, fmt.Errorf("reading device: %v", err)
+		}
+
+		// Verify we got an integer.
+		selection, err = strconv.Atoi(input)
+		if err != nil {
+			fmt.Fprintf(out, "Invalid input '%s'\n", input)
+			continue
+		}
+
+		// Verify selection is within range.
+		if selection < 1 || selection > len(endpoints) {
+			fmt.Fprintf(out, "Invalid value %d. Valid values: 1-%d\n", selection, len(endpoints))
+			continue
+		}
+		break
+	}
+
+	return &endpoints[selection-1], nil
+}
+
+// getFactor returns the MFA factor to use. The preferred factor is used if it is available,
+// otherwise the first available supported factor is used.
+func getFactor(factors []Factor, preferred string) (string, error) {
+	var available []string
+	for _, f := range factors {
+		if f.Status != "available" {
+			continue
+		}
+		if f.Type != FactorTOTP && f.Type != FactorPush {
+			log.Debugf("Skipping unsupported MFA factor %s", f.Type)
+			continue
+		}
+		if f.Type == preferred {
+			return f.Type, nil
+		}
+		available = append(available, f.Type)
+	}
+
+	if len(available) == 0 {
+		return "", errors.New("no supported MFA factor available")
+	}
+	if preferred != "" {
+		log.Warnf("MFA factor %s not available, using %s", preferred, available[0])
+	}
+
+	return available[0], nil
+}
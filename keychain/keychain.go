@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"syscall"
 
+	"errors"
 	"github.com/allcloud-io/clisso/log"
 	keyring "github.com/zalando/go-keyring"
 	"golang.org/x/term"
@@ -58,6 +59,15 @@ func (DefaultKeychain) Get(provider string) (pw []byte, err error) {
 	return pass, nil
 }
 
+// Delete removes the password of the given provider from the keychain. A provider without a
+// stored password isn't an error.
+func Delete(provider string) error {
+	if err := keyring.Delete(KeyChainName, provider); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
 func set(provider string, password []byte) (err error) {
 	return keyring.Set(KeyChainName, provider, string(password))
 }
@@ -58,6 +58,14 @@ func TestCycle(t *testing.T) {
 			if string(test.password) != string(retrievedPass) {
 				t.Errorf("expected %s, received %s", test.password, retrievedPass)
 			}
+
+			if err := Delete(test.name); err != nil {
+				t.Errorf("unexpected error %+v", err)
+			}
+			// Deleting a missing password isn't an error
+			if err := Delete(test.name); err != nil {
+				t.Errorf("unexpected error %+v", err)
+			}
 		})
 	}
 }
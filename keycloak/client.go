@@ -0,0 +1,44 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package keycloak
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/allcloud-io/clisso/htmlform"
+)
+
+// Client represents a Keycloak login client.
+type Client struct {
+	*htmlform.Client
+	Realm string
+}
+
+// InitSSO starts an IdP-initiated SAML login for the client with the given URL name and returns
+// the first page of the login flow. The URL name is the "IDP-Initiated SSO URL name" configured
+// on the Keycloak client.
+func (c *Client) InitSSO(client string) (*htmlform.Page, error) {
+	u := fmt.Sprintf("%s/realms/%s/protocol/saml/clients/%s", c.BaseURL,
+		url.PathEscape(c.Realm), url.PathEscape(client))
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	return c.Load(req)
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL, realm string) (*Client, error) {
+	c, err := htmlform.NewClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{Client: c, Realm: realm}, nil
+}
@@ -0,0 +1,90 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package keycloak
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+const loginPage = `<html><body>
+%s
+<form id="kc-form-login" onsubmit="login.disabled = true; return true;"
+	action="/realms/test/login-actions/authenticate?session_code=abc&amp;execution=def&amp;client_id=urn%%3Aamazon%%3Awebservices&amp;tab_id=ghi" method="post">
+	<input tabindex="1" id="username" name="username" value="" type="text" autofocus autocomplete="off">
+	<input tabindex="2" id="password" name="password" type="password" autocomplete="off">
+	<input type="hidden" id="id-hidden-input" name="credentialId">
+	<input tabindex="4" name="login" id="kc-login" type="submit" value="Sign In">
+</form></body></html>`
+
+const samlPage = `<html><body onload="document.forms[0].submit()">
+<form name="saml-post-binding" method="post" action="https://signin.aws.amazon.com/saml">
+	<input type="hidden" name="SAMLResponse" value="fake_assertion">
+	<noscript><input type="submit" value="Continue"></noscript>
+</form></body></html>`
+
+func getTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realms/test/protocol/saml/clients/aws", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "AUTH_SESSION_ID", Value: "session", Path: "/realms/test/"})
+		fmt.Fprintf(w, loginPage, "")
+	})
+	mux.HandleFunc("/realms/test/login-actions/authenticate", func(w http.ResponseWriter, r *http.Request) {
+		_, err := r.Cookie("AUTH_SESSION_ID")
+		assert.Nil(t, err)
+		assert.Equal(t, "def", r.URL.Query().Get("execution"))
+		if r.PostFormValue("username") != "user" || r.PostFormValue("password") != "secret" {
+			fmt.Fprintf(w, loginPage, `<span id="input-error" class="pf-c-form__helper-text">Invalid username or password.</span>`)
+			return
+		}
+		fmt.Fprint(w, samlPage)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestLogin(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, "test")
+	assert.Nil(t, err)
+
+	assertion, err := login(c, &loginParams{ClientID: "aws", Username: "user", Password: "secret"})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	ts := getTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, "test")
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{ClientID: "aws", Username: "user", Password: "wrong"})
+	assert.EqualError(t, err, "login failed: Invalid username or password.")
+}
+
+func TestLoginConfigureTOTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><form id="kc-totp-settings-form" method="post"></form></body></html>`)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL, "test")
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{ClientID: "aws", Username: "user", Password: "secret"})
+	assert.EqualError(t, err, "OTP setup is required, please log in to Keycloak using a browser first")
+}
@@ -0,0 +1,188 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package keycloak
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/htmlform"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/icza/gog"
+)
+
+const (
+	// Selectors of the forms making up the Keycloak login flow.
+	loginFormSelector         = "form#kc-form-login"
+	otpFormSelector           = "form#kc-otp-login-form"
+	configureTOTPFormSelector = "form#kc-totp-settings-form"
+	updatePasswordSelector    = "form#kc-passwd-update-form"
+
+	// maxPages limits the number of pages followed during a single login.
+	maxPages = 10
+)
+
+var (
+	keyChain = keychain.DefaultKeychain{}
+)
+
+func init() {
+	idp.Register("keycloak", Provider{})
+}
+
+// Provider implements idp.Provider for Keycloak.
+type Provider struct{}
+
+// Assertion logs in to Keycloak and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from Keycloak")
+
+	p, err := config.GetKeycloakProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetKeycloakApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c, err := NewClient(p.BaseURL, p.Realm)
+	if err != nil {
+		return "", fmt.Errorf("initializing Keycloak client: %v", err)
+	}
+
+	user := p.Username
+	if user == "" {
+		user, err = prompt.Ask("Keycloak username: ", r.Interactive)
+		if err != nil {
+			return "", fmt.Errorf("reading username: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	log.WithFields(log.Fields{
+		"Username": user,
+		// print password only in Trace Log Level
+		"Password": gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"Realm":    p.Realm,
+		"ClientID": a.ClientID,
+	}).Debug("Logging in to Keycloak")
+
+	return login(c, &loginParams{
+		ClientID:    a.ClientID,
+		Username:    user,
+		Password:    string(pass),
+		Interactive: r.Interactive,
+	})
+}
+
+type loginParams struct {
+	ClientID    string
+	Username    string
+	Password    string
+	Interactive bool
+}
+
+// login walks through the pages of the Keycloak login flow until a page carrying the SAML
+// response is returned.
+func login(c *Client, p *loginParams) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	page, err := c.InitSSO(p.ClientID)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("starting login: %v", err)
+	}
+
+	var passwordSent, otpSent bool
+	for i := 0; i < maxPages; i++ {
+		if assertion := page.SAMLResponse(); assertion != "" {
+			return assertion, nil
+		}
+
+		form, err := page.Form(loginFormSelector)
+		if err != nil {
+			return "", err
+		}
+		if form != nil {
+			if passwordSent {
+				return "", fmt.Errorf("login failed: %s", pageError(page))
+			}
+			passwordSent = true
+			form.Values.Set("username", p.Username)
+			form.Values.Set("password", p.Password)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("submitting login form: %v", err)
+			}
+			continue
+		}
+
+		form, err = page.Form(otpFormSelector)
+		if err != nil {
+			return "", err
+		}
+		if form != nil {
+			if otpSent {
+				return "", fmt.Errorf("OTP verification failed: %s", pageError(page))
+			}
+			otpSent = true
+
+			otp, err := prompt.Ask("Please enter the OTP from your MFA device: ", p.Interactive)
+			if err != nil {
+				return "", fmt.Errorf("reading OTP: %v", err)
+			}
+			form.Values.Set("otp", otp)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("verifying OTP: %v", err)
+			}
+			continue
+		}
+
+		if page.Doc.Find(configureTOTPFormSelector).Length() > 0 {
+			return "", errors.New("OTP setup is required, please log in to Keycloak using a browser first")
+		}
+		if page.Doc.Find(updatePasswordSelector).Length() > 0 {
+			return "", errors.New("password update is required, please log in to Keycloak using a browser first")
+		}
+
+		return "", fmt.Errorf("unexpected login page: %s", pageError(page))
+	}
+
+	return "", errors.New("no SAML response received from Keycloak")
+}
+
+// pageError returns the error or info message shown on a Keycloak page.
+func pageError(page *htmlform.Page) string {
+	msg := strings.TrimSpace(page.Doc.Find(`#input-error, .alert-error, .kc-feedback-text, #kc-page-title`).First().Text())
+	if msg == "" {
+		return "unknown error"
+	}
+	return msg
+}
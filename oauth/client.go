@@ -0,0 +1,211 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+
+// Package oauth implements the parts of OAuth 2.0 and OpenID Connect shared by identity providers:
+// discovering an issuer, calling its token endpoint, the device authorization flow and PKCE.
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/allcloud-io/clisso/log"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+
+	ErrAuthorizationPending = "authorization_pending"
+	ErrSlowDown             = "slow_down"
+	ErrExpiredToken         = "expired_token"
+	ErrAccessDenied         = "access_denied"
+)
+
+// Client represents an OAuth 2.0 client of an OpenID Connect issuer.
+type Client struct {
+	http.Client
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Discovery    *Discovery
+}
+
+// Discovery represents the OpenID provider metadata published by an issuer.
+type Discovery struct {
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// Error represents an OAuth 2.0 error response.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// TokenResponse represents the result of a call to the token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// DeviceAuthorizationResponse represents the result of a call to the device authorization
+// endpoint.
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// Discover fetches the provider metadata from the well-known endpoint of the issuer.
+func (c *Client) Discover() error {
+	req, err := http.NewRequest(http.MethodGet, c.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	var d Discovery
+	if err = json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return fmt.Errorf("parsing HTTP response: %v", err)
+	}
+	if d.TokenEndpoint == "" {
+		return errors.New("issuer doesn't publish a token endpoint")
+	}
+	c.Discovery = &d
+
+	return nil
+}
+
+// AuthorizationURL returns the URL the user is sent to in order to authorize clisso.
+func (c *Client) AuthorizationURL(redirectURI, scope, state, codeChallenge string) (string, error) {
+	if c.Discovery.AuthorizationEndpoint == "" {
+		return "", errors.New("issuer doesn't support the authorization code flow")
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {scope},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(c.Discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return c.Discovery.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// StartDeviceAuthorization starts the device authorization flow.
+func (c *Client) StartDeviceAuthorization(scope string) (*DeviceAuthorizationResponse, error) {
+	if c.Discovery.DeviceAuthorizationEndpoint == "" {
+		return nil, errors.New("issuer doesn't support the device authorization flow")
+	}
+
+	var resp DeviceAuthorizationResponse
+	if err := c.post(c.Discovery.DeviceAuthorizationEndpoint, url.Values{"scope": {scope}}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Token calls the token endpoint with the given parameters. The client credentials are added by
+// Token. Errors returned by the endpoint are returned as *Error.
+func (c *Client) Token(params url.Values) (*TokenResponse, error) {
+	var resp TokenResponse
+	if err := c.post(c.Discovery.TokenEndpoint, params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// post sends a form to an endpoint of the issuer and decodes the JSON response into v.
+func (c *Client) post(endpoint string, params url.Values, v interface{}) error {
+	form := url.Values{"client_id": {c.ClientID}}
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+	for k, vs := range params {
+		form[k] = vs
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr Error
+		if err = json.NewDecoder(resp.Body).Decode(&oauthErr); err != nil || oauthErr.Code == "" {
+			return errors.New(resp.Status)
+		}
+		return &oauthErr
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return nil
+}
+
+// NewClient creates a new Client and returns a pointer to it. Discover must be called before
+// using the client.
+func NewClient(issuer, clientID, clientSecret string) *Client {
+	return &Client{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+}
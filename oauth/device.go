@@ -0,0 +1,96 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/allcloud-io/clisso/internal/loopback"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+)
+
+var (
+	// DefaultInterval is the time to wait between polls for the token during the device flow if
+	// the device authorization response doesn't specify one. Replaced in tests.
+	DefaultInterval = 5 * time.Second
+
+	// OpenURL opens a URL in a browser. Replaced in tests.
+	OpenURL = loopback.Open
+)
+
+// TokenFunc requests a token for a device code. Errors returned by the authorization server must
+// be returned as *Error, so that a pending authorization can be told apart from a failure.
+type TokenFunc func(deviceCode string) (*TokenResponse, error)
+
+// DeviceFlow runs the device authorization flow, requesting the given scope.
+func DeviceFlow(c *Client, scope string, interactive bool) (*TokenResponse, error) {
+	auth, err := c.StartDeviceAuthorization(scope)
+	if err != nil {
+		return nil, fmt.Errorf("starting device authorization: %v", err)
+	}
+
+	return AwaitDeviceToken(auth, interactive, func(deviceCode string) (*TokenResponse, error) {
+		return c.Token(url.Values{
+			"grant_type":  {GrantTypeDeviceCode},
+			"device_code": {deviceCode},
+		})
+	})
+}
+
+// AwaitDeviceToken asks the user to approve a started device authorization and polls for the
+// token using the given function until the authorization is approved, denied or expired.
+func AwaitDeviceToken(auth *DeviceAuthorizationResponse, interactive bool, token TokenFunc) (*TokenResponse, error) {
+	// Print to stderr since stdout may carry the credentials.
+	fmt.Fprintf(os.Stderr, "Please authorize the request in your browser. If it doesn't open, visit:\n%s\n", auth.VerificationURI)
+	fmt.Fprintf(os.Stderr, "and enter the code: %s\n", auth.UserCode)
+	if auth.VerificationURIComplete != "" {
+		if err := OpenURL(auth.VerificationURIComplete); err != nil {
+			log.WithError(err).Debug("Could not open browser")
+		}
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+
+	s := spinner.New(interactive)
+	s.Start()
+	defer s.Stop()
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		resp, err := token(auth.DeviceCode)
+		var oauthErr *Error
+		if errors.As(err, &oauthErr) {
+			switch oauthErr.Code {
+			case ErrAuthorizationPending:
+				continue
+			case ErrSlowDown:
+				interval += 5 * time.Second
+				continue
+			case ErrExpiredToken:
+				return nil, errors.New("device authorization expired")
+			case ErrAccessDenied:
+				return nil, errors.New("device authorization was denied")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting token: %v", err)
+		}
+
+		return resp, nil
+	}
+
+	return nil, errors.New("device authorization expired")
+}
@@ -0,0 +1,95 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+// getDeviceTestServer returns a stand-in for an issuer which answers the device code grant with
+// the given error after the first poll, or with tokens if the error is empty.
+func getDeviceTestServer(t *testing.T, result string) *httptest.Server {
+	var ts *httptest.Server
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":"%[1]s","token_endpoint":"%[1]s/token","device_authorization_endpoint":"%[1]s/device"}`, ts.URL)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "clisso", r.PostFormValue("client_id"))
+		assert.Equal(t, "openid offline_access", r.PostFormValue("scope"))
+		fmt.Fprint(w, `{"device_code":"device","user_code":"ABCD","verification_uri":"https://idp.example.com/device",`+
+			`"verification_uri_complete":"https://idp.example.com/device?code=ABCD","expires_in":600,"interval":0}`)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, GrantTypeDeviceCode, r.PostFormValue("grant_type"))
+		assert.Equal(t, "device", r.PostFormValue("device_code"))
+		polls++
+		switch {
+		case polls < 2:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"authorization_pending"}`)
+		case result != "":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":"%s"}`, result)
+		default:
+			fmt.Fprint(w, `{"access_token":"access","id_token":"id_token_device","token_type":"Bearer","expires_in":3600}`)
+		}
+	})
+
+	ts = httptest.NewServer(mux)
+	return ts
+}
+
+func TestDeviceFlow(t *testing.T) {
+	defer func(d time.Duration) { DefaultInterval = d }(DefaultInterval)
+	DefaultInterval = time.Millisecond
+
+	open := OpenURL
+	defer func() { OpenURL = open }()
+	OpenURL = func(u string) error {
+		assert.Equal(t, "https://idp.example.com/device?code=ABCD", u)
+		return nil
+	}
+
+	cases := []struct {
+		Name          string
+		Result        string
+		ExpectedError string
+	}{
+		{Name: "Success"},
+		{Name: "Denied", Result: ErrAccessDenied, ExpectedError: "device authorization was denied"},
+		{Name: "Expired", Result: ErrExpiredToken, ExpectedError: "device authorization expired"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ts := getDeviceTestServer(t, tc.Result)
+			defer ts.Close()
+
+			c := NewClient(ts.URL+"/", "clisso", "")
+			assert.Nil(t, c.Discover())
+
+			resp, err := DeviceFlow(c, "openid offline_access", false)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "id_token_device", resp.IDToken)
+		})
+	}
+}
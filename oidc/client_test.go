@@ -0,0 +1,141 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+// testIssuer is a stand-in for an OIDC issuer.
+type testIssuer struct {
+	*httptest.Server
+	challenge string
+	refreshes int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	ti := &testIssuer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":"%[1]s","authorization_endpoint":"%[1]s/authorize","token_endpoint":"%[1]s/token"}`, ti.URL)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "clisso", r.PostFormValue("client_id"))
+		switch r.PostFormValue("grant_type") {
+		case oauth.GrantTypeAuthorizationCode:
+			assert.Equal(t, "auth_code", r.PostFormValue("code"))
+			sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+			assert.Equal(t, ti.challenge, base64.RawURLEncoding.EncodeToString(sum[:]))
+			fmt.Fprint(w, `{"access_token":"access","id_token":"id_token_code","refresh_token":"refresh1","token_type":"Bearer","expires_in":3600}`)
+		case oauth.GrantTypeRefreshToken:
+			ti.refreshes++
+			if r.PostFormValue("refresh_token") != "refresh1" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"access","id_token":"id_token_refresh","refresh_token":"refresh1","token_type":"Bearer","expires_in":3600}`)
+		default:
+			t.Errorf("unexpected grant type %s", r.PostFormValue("grant_type"))
+		}
+	})
+
+	ti.Server = httptest.NewServer(mux)
+	return ti
+}
+
+func setup(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	open := openURL
+	t.Cleanup(func() { openURL = open })
+}
+
+func TestAuthCodeFlow(t *testing.T) {
+	setup(t)
+
+	ti := newTestIssuer(t)
+	defer ti.Close()
+
+	// Instead of opening a browser, redirect to the listener like the issuer would.
+	openURL = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		assert.Nil(t, err)
+		q := u.Query()
+		assert.Equal(t, "/authorize", u.Path)
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.Equal(t, "S256", q.Get("code_challenge_method"))
+		ti.challenge = q.Get("code_challenge")
+
+		go func() {
+			resp, err := http.Get(q.Get("redirect_uri") + "?" + url.Values{
+				"code":  {"auth_code"},
+				"state": {q.Get("state")},
+			}.Encode())
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+		return nil
+	}
+
+	c := oauth.NewClient(ti.URL, "clisso", "")
+	p := &params{Flow: FlowAuthCode, Scope: "openid offline_access"}
+
+	token, err := getIDToken(c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, "id_token_code", token)
+
+	// The cached refresh token is used without opening the browser.
+	openURL = func(string) error {
+		t.Error("browser opened although a refresh token is cached")
+		return nil
+	}
+	token, err = getIDToken(c, p)
+	assert.Nil(t, err)
+	assert.Equal(t, "id_token_refresh", token)
+	assert.Equal(t, 1, ti.refreshes)
+}
+
+func TestAuthCodeFlowError(t *testing.T) {
+	setup(t)
+
+	ti := newTestIssuer(t)
+	defer ti.Close()
+
+	openURL = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		assert.Nil(t, err)
+		q := u.Query()
+		go func() {
+			resp, err := http.Get(q.Get("redirect_uri") + "?" + url.Values{
+				"error":             {"access_denied"},
+				"error_description": {"User is not assigned to the app"},
+				"state":             {q.Get("state")},
+			}.Encode())
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		}()
+		return nil
+	}
+
+	c := oauth.NewClient(ti.URL, "clisso", "")
+	_, err := getIDToken(c, &params{Flow: FlowAuthCode, Scope: "openid"})
+	assert.EqualError(t, err, "authorization failed: access_denied: User is not assigned to the app")
+}
@@ -0,0 +1,263 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/allcloud-io/clisso/aws"
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/loopback"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/oauth"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/allcloud-io/clisso/tokencache"
+)
+
+const (
+	FlowAuthCode = "auth-code"
+	FlowDevice   = "device"
+
+	// DefaultScope is requested unless the provider configures other scopes.
+	DefaultScope = "openid"
+	// DefaultPort is the port of the redirect listener unless configured otherwise.
+	DefaultPort = 21844
+
+	// sessionName is the role session name used when assuming roles.
+	sessionName = "clisso"
+)
+
+var (
+	// LoginTimeout is the time the user has to complete the login in the browser.
+	LoginTimeout = 3 * time.Minute
+
+	// openURL opens a URL in a browser. Replaced in tests.
+	openURL = loopback.Open
+)
+
+func init() {
+	idp.RegisterCredentials("oidc", Provider{})
+}
+
+// Provider implements idp.CredentialsProvider for OpenID Connect identity providers. The ID token
+// is exchanged for AWS credentials using AssumeRoleWithWebIdentity.
+type Provider struct{}
+
+// refreshToken is a cached refresh token.
+type refreshToken struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Credentials logs in to the OIDC issuer and assumes the role configured for the requested app.
+func (Provider) Credentials(r *idp.Request) (*aws.Credentials, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting credentials using OIDC")
+
+	p, err := config.GetOIDCProvider(r.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetOIDCApp(r.App)
+	if err != nil {
+		return nil, fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c := oauth.NewClient(p.Issuer, p.ClientID, p.ClientSecret)
+
+	port := p.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	scope := p.Scope
+	if scope == "" {
+		scope = DefaultScope
+	}
+
+	idToken, err := getIDToken(c, &params{
+		Flow:        p.Flow,
+		Scope:       scope,
+		Port:        port,
+		Interactive: r.Interactive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := spinner.New(r.Interactive)
+
+	s.Start()
+	creds, err := aws.AssumeRoleWithWebIdentity(a.RoleARN, idToken, sessionName, r.AWSRegion, r.Duration)
+	s.Stop()
+
+	if err != nil && err.Error() == aws.ErrDurationExceeded {
+		log.Warn(aws.DurationExceededMessage)
+		s.Start()
+		creds, err = aws.AssumeRoleWithWebIdentity(a.RoleARN, idToken, sessionName, r.AWSRegion, 3600)
+		s.Stop()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+type params struct {
+	Flow        string
+	Scope       string
+	Port        int
+	Interactive bool
+}
+
+// getIDToken returns an ID token from the issuer. A cached refresh token is used if possible,
+// otherwise the user logs in using the configured flow.
+func getIDToken(c *oauth.Client, p *params) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	err := c.Discover()
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("discovering OIDC issuer %s: %v", c.Issuer, err)
+	}
+
+	key := cacheKey(c)
+
+	var rt refreshToken
+	ok, err := tokencache.Load(key, &rt)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't read cached refresh token")
+	}
+	if ok && rt.RefreshToken != "" {
+		s.Start()
+		resp, err := c.Token(url.Values{
+			"grant_type":    {oauth.GrantTypeRefreshToken},
+			"refresh_token": {rt.RefreshToken},
+			"scope":         {p.Scope},
+		})
+		s.Stop()
+		if err == nil && resp.IDToken != "" {
+			log.Debug("Got ID token using cached refresh token")
+			saveRefreshToken(key, resp.RefreshToken)
+			return resp.IDToken, nil
+		}
+		log.WithError(err).Debug("Couldn't use cached refresh token, logging in again")
+		if err = tokencache.Delete(key); err != nil {
+			return "", err
+		}
+	}
+
+	var resp *oauth.TokenResponse
+	switch p.Flow {
+	case FlowDevice:
+		resp, err = oauth.DeviceFlow(c, p.Scope, p.Interactive)
+	case FlowAuthCode, "":
+		resp, err = authCodeFlow(c, p)
+	default:
+		return "", fmt.Errorf("unsupported OIDC flow '%s'", p.Flow)
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.IDToken == "" {
+		return "", errors.New("no ID token returned, make sure the openid scope is requested")
+	}
+
+	saveRefreshToken(key, resp.RefreshToken)
+
+	return resp.IDToken, nil
+}
+
+// authCodeFlow runs the authorization code flow with PKCE, receiving the authorization code on a
+// local redirect listener.
+func authCodeFlow(c *oauth.Client, p *params) (*oauth.TokenResponse, error) {
+	verifier, err := oauth.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	state, err := oauth.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	challenge := oauth.CodeChallenge(verifier)
+
+	l, err := listen(p.Port, state)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	authURL, err := c.AuthorizationURL(l.URL(), p.Scope, state, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	// Print to stderr since stdout may carry the credentials.
+	fmt.Fprintf(os.Stderr, "Please log in using your browser. If it doesn't open, visit:\n%s\n", authURL)
+	if err = openURL(authURL); err != nil {
+		log.WithError(err).Debug("Could not open browser")
+	}
+
+	s := spinner.New(p.Interactive)
+	s.Start()
+	code, err := l.Wait(LoginTimeout)
+	s.Stop()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Start()
+	resp, err := c.Token(url.Values{
+		"grant_type":    {oauth.GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {l.URL()},
+		"code_verifier": {verifier},
+	})
+	s.Stop()
+	if err != nil {
+		return nil, fmt.Errorf("exchanging authorization code: %v", err)
+	}
+
+	return resp, nil
+}
+
+// saveRefreshToken caches the refresh token, if the issuer returned one.
+func saveRefreshToken(key, token string) {
+	if token == "" {
+		return
+	}
+	if err := tokencache.Save(key, &refreshToken{RefreshToken: token}); err != nil {
+		log.WithError(err).Warn("Couldn't cache refresh token")
+	}
+}
+
+// cacheKey returns the cache key of the refresh token for the issuer and client.
+func cacheKey(c *oauth.Client) string {
+	return fmt.Sprintf("oidc-refresh-token:%s:%s", c.Issuer, c.ClientID)
+}
+
+// DeleteCache removes the refresh token cached for the given provider.
+func DeleteCache(provider string) error {
+	p, err := config.GetOIDCProvider(provider)
+	if err != nil {
+		return fmt.Errorf("reading provider config: %v", err)
+	}
+
+	if err := tokencache.Delete(cacheKey(oauth.NewClient(p.Issuer, p.ClientID, ""))); err != nil {
+		return fmt.Errorf("deleting cached refresh token: %v", err)
+	}
+
+	return nil
+}
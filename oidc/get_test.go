@@ -0,0 +1,35 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package oidc
+
+import (
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/oauth"
+	"github.com/allcloud-io/clisso/tokencache"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteCache(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	viper.Set("providers.test-oidc-delete", map[string]interface{}{
+		"type":      "oidc",
+		"issuer":    "https://example.com/",
+		"client-id": "client",
+	})
+
+	key := cacheKey(oauth.NewClient("https://example.com", "client", ""))
+	assert.Nil(t, tokencache.Save(key, &refreshToken{RefreshToken: "token"}))
+
+	assert.Nil(t, DeleteCache("test-oidc-delete"))
+
+	ok, err := tokencache.Load(key, &refreshToken{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
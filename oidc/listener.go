@@ -0,0 +1,48 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/allcloud-io/clisso/internal/loopback"
+	"github.com/allcloud-io/clisso/oauth"
+)
+
+// CallbackPath is the path of the redirect URI.
+const CallbackPath = "/callback"
+
+// listen starts a redirect listener on 127.0.0.1 and the given port, receiving the authorization
+// code of the authorization request with the given state. Port 0 picks a free port.
+func listen(port int, state string) (*loopback.Listener, error) {
+	return loopback.NewListener(port, CallbackPath, "authorization response", handleCallback(state))
+}
+
+// handleCallback returns a handler receiving the authorization response the browser is
+// redirected with.
+func handleCallback(state string) loopback.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) *loopback.Result {
+		q := r.URL.Query()
+
+		// Ignore requests which don't belong to our authorization request.
+		if q.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return nil
+		}
+
+		if e := q.Get("error"); e != "" {
+			return &loopback.Result{
+				Err: fmt.Errorf("authorization failed: %v", &oauth.Error{Code: e, Description: q.Get("error_description")}),
+			}
+		}
+		if code := q.Get("code"); code != "" {
+			return &loopback.Result{Value: code}
+		}
+		return &loopback.Result{Err: errors.New("no authorization code received")}
+	}
+}
@@ -7,12 +7,16 @@ package okta
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -21,20 +25,84 @@ import (
 )
 
 const (
-	StatusSuccess     = "SUCCESS"
-	StatusMFARequired = "MFA_REQUIRED"
+	StatusSuccess      = "SUCCESS"
+	StatusMFARequired  = "MFA_REQUIRED"
+	StatusMFAChallenge = "MFA_CHALLENGE"
+	StatusMFAEnroll    = "MFA_ENROLL"
+	StatusLockedOut    = "LOCKED_OUT"
+	// StatusPasswordExpired means the password must be changed before the login can continue.
+	StatusPasswordExpired = "PASSWORD_EXPIRED"
+	// StatusPasswordWarn means the password expires soon. The password can be changed, or the
+	// change can be skipped.
+	StatusPasswordWarn = "PASSWORD_WARN"
+	// StatusUnauthenticated means the user must enter their credentials again, e.g. when an app
+	// requires re-authentication.
+	StatusUnauthenticated = "UNAUTHENTICATED"
+
+	// sessionCookie is the name of the cookie holding the Okta session ID.
+	sessionCookie = "sid"
+
+	// maxInterstitials is the maximum number of interstitial pages followed when launching an app.
+	maxInterstitials = 5
 )
 
+var (
+	jsRedirectRegexp  = regexp.MustCompile(`location(?:\.href\s*=|\s*=|\.replace\(|\.assign\()\s*["']([^"']+)["']`)
+	metaRefreshRegexp = regexp.MustCompile(`(?i)^\s*\d+\s*;\s*url\s*=\s*['"]?([^'"]+)['"]?\s*$`)
+)
+
+// ErrorCodePasswordPolicy is the error code returned when a new password doesn't comply with the
+// password policy.
+const ErrorCodePasswordPolicy = "E0000080"
+
+// APIError represents an error returned by the Okta API.
+// https://developer.okta.com/docs/reference/error-codes/
+type APIError struct {
+	StatusCode   int    `json:"-"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorSummary string `json:"errorSummary"`
+	ErrorID      string `json:"errorId"`
+	ErrorCauses  []struct {
+		ErrorSummary string `json:"errorSummary"`
+	} `json:"errorCauses"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.ErrorSummary
+	var causes []string
+	for _, c := range e.ErrorCauses {
+		causes = append(causes, c.ErrorSummary)
+	}
+	if len(causes) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(causes, "; "))
+	}
+	return fmt.Sprintf("%s (%s)", msg, e.ErrorCode)
+}
+
 // Client represents an Okta API client.
 type Client struct {
 	http.Client
 	BaseURL string
+	// RememberDevice asks Okta to remember the device identified by the device token passed to
+	// GetSessionToken when verifying a factor, so that MFA can be skipped on later logins if the
+	// org policy allows it.
+	RememberDevice bool
+	// AccessToken is an OAuth 2.0 access token used by GetAppLinks instead of the session, if set.
+	AccessToken string
 }
 
 // GetSessionTokenParams represents the parameters for GetSessionToken.
 type GetSessionTokenParams struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string       `json:"username"`
+	Password string       `json:"password"`
+	Context  *AuthContext `json:"context,omitempty"`
+	// StateToken continues an existing transaction which requires the credentials again.
+	StateToken string `json:"stateToken,omitempty"`
+}
+
+// AuthContext represents the context of an authentication request.
+type AuthContext struct {
+	DeviceToken string `json:"deviceToken,omitempty"`
 }
 
 // GetSessionTokenResponse represents the result of a call to GetSessionToken.
@@ -44,18 +112,45 @@ type GetSessionTokenResponse struct {
 	StateToken   string    `json:"stateToken"`
 	Status       string    `json:"status"`
 	Embedded     struct {
-		Factors []struct {
-			ID    string `json:"id"`
-			Links struct {
-				Verify struct {
-					Href string `json:"href"`
-				} `json:"verify"`
-			} `json:"_links"`
-			FactorType string `json:"factorType"`
-		} `json:"factors"`
+		Factors []Factor `json:"factors"`
+		Policy  struct {
+			Expiration struct {
+				PasswordExpireDays int `json:"passwordExpireDays"`
+			} `json:"expiration"`
+		} `json:"policy"`
 	} `json:"_embedded"`
 }
 
+// Factor represents an MFA factor enrolled by the user.
+type Factor struct {
+	ID         string `json:"id"`
+	FactorType string `json:"factorType"`
+	Provider   string `json:"provider"`
+	VendorName string `json:"vendorName"`
+	Profile    struct {
+		CredentialID string `json:"credentialId"`
+		PhoneNumber  string `json:"phoneNumber"`
+		Email        string `json:"email"`
+	} `json:"profile"`
+	Links struct {
+		Verify struct {
+			Href string `json:"href"`
+		} `json:"verify"`
+	} `json:"_links"`
+}
+
+// String returns a human readable description of the factor, e.g. "GOOGLE token:software:totp
+// (user@example.com)".
+func (f Factor) String() string {
+	s := fmt.Sprintf("%s %s", f.Provider, f.FactorType)
+	for _, d := range []string{f.Profile.CredentialID, f.Profile.PhoneNumber, f.Profile.Email} {
+		if d != "" {
+			return fmt.Sprintf("%s (%s)", s, d)
+		}
+	}
+	return s
+}
+
 // GetSessionToken performs a login operation against the Okta API and returns a session token upon
 // successful login.
 //
@@ -80,7 +175,64 @@ func (c *Client) GetSessionToken(p *GetSessionTokenParams) (*GetSessionTokenResp
 
 	data, err := c.doRequest(req)
 	if err != nil {
-		return nil, fmt.Errorf("doing HTTP request: %v", err)
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
+	}
+
+	var resp GetSessionTokenResponse
+	err = json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return &resp, nil
+}
+
+// GetTransactionParams represents the parameters for GetTransaction.
+type GetTransactionParams struct {
+	StateToken string `json:"stateToken"`
+}
+
+// GetTransaction returns the current state of an authentication transaction. Once the transaction
+// completes, the Status field of the response is StatusSuccess and the SessionToken field contains
+// a valid session token.
+func (c *Client) GetTransaction(p *GetTransactionParams) (*GetSessionTokenResponse, error) {
+	return c.postTransaction("/api/v1/authn", p)
+}
+
+// ChangePasswordParams represents the parameters for ChangePassword.
+type ChangePasswordParams struct {
+	StateToken  string `json:"stateToken"`
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// ChangePassword changes the password of the user of a transaction with the status
+// StatusPasswordExpired or StatusPasswordWarn and returns the new state of the transaction.
+func (c *Client) ChangePassword(p *ChangePasswordParams) (*GetSessionTokenResponse, error) {
+	return c.postTransaction("/api/v1/authn/credentials/change_password", p)
+}
+
+// SkipTransaction skips the optional step of a transaction, such as changing a password with the
+// status StatusPasswordWarn, and returns the new state of the transaction.
+func (c *Client) SkipTransaction(p *GetTransactionParams) (*GetSessionTokenResponse, error) {
+	return c.postTransaction("/api/v1/authn/skip", p)
+}
+
+// postTransaction posts the body to an endpoint of the authentication API and returns the state
+// of the transaction.
+func (c *Client) postTransaction(path string, body interface{}) (*GetSessionTokenResponse, error) {
+	h := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
+	}
+	req, err := makeRequest(http.MethodPost, c.BaseURL+path, h, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+
+	data, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
 	}
 
 	var resp GetSessionTokenResponse
@@ -92,11 +244,31 @@ func (c *Client) GetSessionToken(p *GetSessionTokenParams) (*GetSessionTokenResp
 	return &resp, nil
 }
 
+// CancelTransaction cancels an authentication transaction.
+func (c *Client) CancelTransaction(p *GetTransactionParams) error {
+	h := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
+	}
+	req, err := makeRequest(http.MethodPost, c.BaseURL+"/api/v1/authn/cancel", h, p)
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
+	}
+
+	if _, err = c.doRequest(req); err != nil {
+		return fmt.Errorf("doing HTTP request: %w", err)
+	}
+
+	return nil
+}
+
 // VerifyFactorParams represents the parameters for VerifyFactor.
 type VerifyFactorParams struct {
 	FactorID   string `json:"factorId"`
 	StateToken string `json:"stateToken"`
-	PassCode   string `json:"passCode"`
+	PassCode   string `json:"passCode,omitempty"`
+	// NextPassCode is the code following PassCode, required by tokens which are out of sync.
+	NextPassCode string `json:"nextPassCode,omitempty"`
 }
 
 // VerifyFactorResponse represents the result of a call to VerifyFactor.
@@ -123,6 +295,9 @@ func (c *Client) VerifyFactor(p *VerifyFactorParams) (*VerifyFactorResponse, err
 		"Content-Type": "application/json",
 	}
 	url := fmt.Sprintf("%s/api/v1/authn/factors/%s/verify", c.BaseURL, p.FactorID)
+	if c.RememberDevice {
+		url += "?rememberDevice=true"
+	}
 	req, err := makeRequest(http.MethodPost, url, h, p)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %v", err)
@@ -130,7 +305,7 @@ func (c *Client) VerifyFactor(p *VerifyFactorParams) (*VerifyFactorResponse, err
 
 	data, err := c.doRequest(req)
 	if err != nil {
-		return nil, fmt.Errorf("doing HTTP request: %v", err)
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
 	}
 
 	var resp VerifyFactorResponse
@@ -142,21 +317,238 @@ func (c *Client) VerifyFactor(p *VerifyFactorParams) (*VerifyFactorResponse, err
 	return &resp, nil
 }
 
+// ResendFactor asks Okta to deliver a new code for an SMS or voice call factor. The PassCode field
+// of the params is ignored.
+func (c *Client) ResendFactor(p *VerifyFactorParams) (*VerifyFactorResponse, error) {
+	h := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
+	}
+	url := fmt.Sprintf("%s/api/v1/authn/factors/%s/verify/resend", c.BaseURL, p.FactorID)
+	req, err := makeRequest(http.MethodPost, url, h, &VerifyFactorParams{FactorID: p.FactorID, StateToken: p.StateToken})
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+
+	data, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
+	}
+
+	var resp VerifyFactorResponse
+	err = json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return &resp, nil
+}
+
+// Session represents an Okta session.
+type Session struct {
+	ID        string    `json:"id"`
+	Login     string    `json:"login"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// GetSession returns the session identified by the session cookie of the client.
+func (c *Client) GetSession() (*Session, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/v1/sessions/me", nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	data, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
+	}
+
+	var resp Session
+	err = json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return &resp, nil
+}
+
+// SessionID returns the value of the session cookie Okta set after launching an app, or an empty
+// string if there is none.
+func (c *Client) SessionID() string {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return ""
+	}
+	for _, cookie := range c.Jar.Cookies(u) {
+		if cookie.Name == sessionCookie {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// SetSessionID sets the session cookie of the client, so that requests are made within an
+// existing session.
+func (c *Client) SetSessionID(sid string) error {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("parsing base URL: %v", err)
+	}
+	c.Jar.SetCookies(u, []*http.Cookie{{Name: sessionCookie, Value: sid, Path: "/"}})
+	return nil
+}
+
+// OpenSession exchanges a session token for a session cookie, which is used by later requests of
+// the client.
+func (c *Client) OpenSession(sessionToken string) error {
+	q := url.Values{
+		"token":       {sessionToken},
+		"redirectUrl": {c.BaseURL + "/"},
+	}
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/login/sessionCookieRedirect?"+q.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending HTTP request: %v", err)
+	}
+	resp.Body.Close()
+
+	if c.SessionID() == "" {
+		return errors.New("no session cookie returned")
+	}
+
+	return nil
+}
+
+// AppLink represents a link to an app assigned to the user.
+type AppLink struct {
+	ID            string `json:"id"`
+	Label         string `json:"label"`
+	LinkURL       string `json:"linkUrl"`
+	AppName       string `json:"appName"`
+	AppInstanceID string `json:"appInstanceId"`
+	SortOrder     int    `json:"sortOrder"`
+}
+
+// GetAppLinks returns the links to the apps assigned to the user of the current session, or of the
+// access token if set.
+func (c *Client) GetAppLinks() ([]AppLink, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/v1/users/me/appLinks", nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	}
+
+	data, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
+	}
+
+	var resp []AppLink
+	err = json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return resp, nil
+}
+
 // LaunchAppParams represents the parameters for LaunchApp.
 type LaunchAppParams struct {
 	SessionToken string
 	URL          string
 }
 
-// LaunchApp launches an Okta app and returns a SAML assertion.
-// TODO Error handling
+// StepUpError is returned by LaunchApp when the sign-on policy of the app requires the user to
+// authenticate again, or to verify an MFA factor, before the app can be launched.
+type StepUpError struct {
+	// URL is the URL of the sign-in page.
+	URL string
+	// StateToken identifies the authentication transaction which completes the step-up.
+	StateToken string
+}
+
+func (e *StepUpError) Error() string {
+	return fmt.Sprintf("app requires additional authentication at %s", e.URL)
+}
+
+// LaunchAppError is returned by LaunchApp when Okta returns a page without a SAML assertion.
+type LaunchAppError struct {
+	// URL is the URL of the page returned.
+	URL string
+	// Title is the title of the page returned.
+	Title string
+}
+
+func (e *LaunchAppError) Error() string {
+	if e.Title == "" {
+		return fmt.Sprintf("no SAML assertion returned, got page %s", e.URL)
+	}
+	return fmt.Sprintf("no SAML assertion returned, got page %q at %s", e.Title, e.URL)
+}
+
+// LaunchApp launches an Okta app and returns a SAML assertion. If SessionToken is empty, the
+// session cookie of the client is used instead. Interstitial pages which redirect using a meta
+// refresh or JavaScript are followed. If the app requires step-up authentication, a *StepUpError
+// is returned. If no assertion is returned otherwise, a *LaunchAppError is returned.
 func (c *Client) LaunchApp(p *LaunchAppParams) (*string, error) {
-	url := fmt.Sprintf("%s?sessionToken=%s", p.URL, p.SessionToken)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	u := p.URL
+	if p.SessionToken != "" {
+		u = fmt.Sprintf("%s?sessionToken=%s", p.URL, p.SessionToken)
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("constructing HTTP request: %v", err)
 	}
 
+	for i := 0; ; i++ {
+		page, err := c.loadPage(req)
+		if err != nil {
+			return nil, err
+		}
+
+		// Extract SAML assertion
+		if saml, ok := page.Doc.Find("form#appForm input[name=SAMLResponse]").Attr("value"); ok && saml != "" {
+			return &saml, nil
+		}
+
+		if st := page.stateToken(); st != "" {
+			log.WithField("url", page.URL).Debug("App requires step-up authentication")
+			return nil, &StepUpError{URL: page.URL.String(), StateToken: st}
+		}
+
+		next, err := page.interstitial()
+		if err != nil {
+			return nil, fmt.Errorf("following interstitial page: %v", err)
+		}
+		if next == nil || i == maxInterstitials {
+			return nil, &LaunchAppError{
+				URL:   page.URL.String(),
+				Title: strings.TrimSpace(page.Doc.Find("title").First().Text()),
+			}
+		}
+		log.WithField("url", next.URL).Debug("Following interstitial page")
+		req = next
+	}
+}
+
+// page is an HTML page returned when launching an app.
+type page struct {
+	URL  *url.URL
+	Body []byte
+	Doc  *goquery.Document
+}
+
+// loadPage sends a request and parses the HTML page returned.
+func (c *Client) loadPage(req *http.Request) (*page, error) {
 	resp, err := c.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("sending GET to app's root endpoint: %v", err)
@@ -167,45 +559,136 @@ func (c *Client) LaunchApp(p *LaunchAppParams) (*string, error) {
 		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading HTTP response: %v", err)
+	}
+
 	// Parse HTML
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("loading HTML document: %v", err)
 	}
 
-	// Extract SAML assertion
-	var saml string
-	doc.Find("form#appForm input[name=SAMLResponse]").Each(func(i int, s *goquery.Selection) {
-		saml, _ = s.Attr("value")
+	return &page{URL: resp.Request.URL, Body: body, Doc: doc}, nil
+}
+
+// stateToken returns the state token of an Okta sign-in page, or an empty string if the page isn't
+// a sign-in page.
+func (p *page) stateToken() string {
+	if m := stateTokenRegexp.FindSubmatch(p.Body); m != nil {
+		return unescapeJS(string(m[1]))
+	}
+	return p.URL.Query().Get("stateToken")
+}
+
+// interstitial returns the request continuing from an interstitial page, which redirects using a
+// meta refresh, JavaScript or an automatically submitted form. It returns nil if the page isn't an
+// interstitial page.
+func (p *page) interstitial() (*http.Request, error) {
+	// Meta refresh
+	var target string
+	p.Doc.Find("meta").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if equiv, _ := s.Attr("http-equiv"); !strings.EqualFold(equiv, "refresh") {
+			return true
+		}
+		content, _ := s.Attr("content")
+		if m := metaRefreshRegexp.FindStringSubmatch(content); m != nil {
+			target = m[1]
+			return false
+		}
+		return true
+	})
+
+	// JavaScript redirect
+	if target == "" {
+		p.Doc.Find("script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+			if m := jsRedirectRegexp.FindStringSubmatch(s.Text()); m != nil {
+				target = unescapeJS(m[1])
+				return false
+			}
+			return true
+		})
+	}
+
+	if target != "" {
+		u, err := p.URL.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+
+	// Automatically submitted form
+	form := p.Doc.Find("form").First()
+	if form.Length() == 0 || !bytes.Contains(p.Body, []byte(".submit()")) {
+		return nil, nil
+	}
+	action, _ := form.Attr("action")
+	u, err := p.URL.Parse(action)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	form.Find("input[name]").Each(func(i int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		value, _ := s.Attr("value")
+		values.Add(name, value)
 	})
+	if method, _ := form.Attr("method"); !strings.EqualFold(method, http.MethodPost) {
+		u.RawQuery = values.Encode()
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	return &saml, nil
+	return req, nil
+}
+
+// unescapeJS replaces the escape sequences Okta uses in JavaScript strings.
+func unescapeJS(s string) string {
+	s = jsEscapeRegexp.ReplaceAllStringFunc(s, func(e string) string {
+		b, _ := hex.DecodeString(e[2:])
+		return string(b)
+	})
+	return strings.ReplaceAll(s, `\/`, "/")
 }
 
 // doRequest gets a pointer to an HTTP request and an HTTP client, executes the request
 // using the client, handles any HTTP-related errors and returns any data as a string.
 func (c *Client) doRequest(r *http.Request) (string, error) {
 	resp, err := c.Do(r)
+	if err != nil {
+		log.WithError(err).WithField("url", r.URL).Trace("Sending HTTP request failed")
+		return "", fmt.Errorf("sending HTTP request: %v", err)
+	}
 	log.WithFields(log.Fields{
 		"status": resp.Status,
 		"url":    resp.Request.URL,
 		"host":   resp.Request.Host,
 		"code":   resp.StatusCode,
 		"method": resp.Request.Method,
-	}).WithError(err).Trace("HTTP request sent")
+	}).Trace("HTTP request sent")
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("sending HTTP request: %v", err)
+		return "", fmt.Errorf("reading HTTP response: %v", err)
 	}
 
 	if resp.StatusCode != 200 {
-		return "", errors.New(resp.Status)
+		// Okta describes errors in the response body
+		apiErr := APIError{StatusCode: resp.StatusCode}
+		if err = json.Unmarshal(body, &apiErr); err != nil || apiErr.ErrorCode == "" {
+			return "", errors.New(resp.Status)
+		}
+		return "", &apiErr
 	}
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	b := []byte(body)
-
-	return string(b), err
+	return string(body), nil
 }
 
 // NewClient creates a new Client and returns a pointer to it.
@@ -6,12 +6,19 @@
 package okta
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
+	"context"
+	"github.com/allcloud-io/clisso/internal/testutil"
 	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
 )
 
 var _, _ = log.SetupLogger("panic", "", false, true)
@@ -126,3 +133,499 @@ func TestVerifyFactor(t *testing.T) {
 		t.Errorf("Wrong response, got: %v, want: %v", resp.ExpiresAt, exp)
 	}
 }
+
+// getChallengeTestServer returns a stand-in for the Okta API verifying an SMS factor with the
+// code 123456.
+func getChallengeTestServer(t *testing.T, sent *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/authn/factors/sms_id/verify", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p["stateToken"])
+
+		switch p["passCode"] {
+		case "":
+			*sent++
+			fmt.Fprint(w, `{"status":"MFA_CHALLENGE","factorResult":"CHALLENGE"}`)
+		case "123456":
+			fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"fake_token"}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errorCode":"E0000068","errorSummary":"Invalid Passcode/Answer"}`)
+		}
+	})
+	mux.HandleFunc("/api/v1/authn/factors/sms_id/verify/resend", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p["stateToken"])
+		*sent++
+		fmt.Fprint(w, `{"status":"MFA_CHALLENGE","factorResult":"CHALLENGE"}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestResendFactor(t *testing.T) {
+	var sent int
+	ts := getChallengeTestServer(t, &sent)
+	defer ts.Close()
+
+	c := Client{BaseURL: ts.URL}
+	resp, err := c.ResendFactor(&VerifyFactorParams{FactorID: "sms_id", StateToken: "fake_state_token", PassCode: "ignored"})
+	assert.Nil(t, err)
+	assert.Equal(t, StatusMFAChallenge, resp.Status)
+	assert.Equal(t, 1, sent)
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	defer func(d time.Duration) { resendInterval = d }(resendInterval)
+
+	f := &Factor{ID: "sms_id", FactorType: MFATypeSMS}
+
+	cases := []struct {
+		Name           string
+		ResendInterval time.Duration
+		Input          string
+		ExpectedSent   int
+		ExpectedError  bool
+	}{
+		{Name: "Code", ResendInterval: time.Minute, Input: "123456\n", ExpectedSent: 1},
+		{Name: "Resend", Input: "resend\n123456\n", ExpectedSent: 2},
+		{Name: "ResendTooEarly", ResendInterval: time.Minute, Input: "resend\n123456\n", ExpectedSent: 1},
+		{Name: "WrongCode", ResendInterval: time.Minute, Input: "654321\n", ExpectedSent: 1, ExpectedError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			resendInterval = tc.ResendInterval
+
+			var sent int
+			ts := getChallengeTestServer(t, &sent)
+			defer ts.Close()
+
+			c := Client{BaseURL: ts.URL}
+			testutil.WithStdin(t, tc.Input, func() {
+				resp, err := verifyChallenge(&c, f, "fake_state_token", false)
+				if tc.ExpectedError {
+					assert.Error(t, err)
+				} else {
+					assert.Nil(t, err)
+					assert.Equal(t, "fake_token", resp.SessionToken)
+				}
+			})
+			assert.Equal(t, tc.ExpectedSent, sent)
+		})
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	// The token is out of sync unless the first code is 111111
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/authn/factors/rsa_id/verify", r.URL.Path)
+		var p VerifyFactorParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p.StateToken)
+
+		switch {
+		case p.PassCode == "111111", p.PassCode == "123456" && p.NextPassCode == "654321":
+			fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"fake_token"}`)
+		case p.PassCode == "123456" && p.NextPassCode == "":
+			fmt.Fprint(w, `{"status":"MFA_CHALLENGE","factorResult":"CHALLENGE"}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	f := &Factor{ID: "rsa_id", FactorType: MFATypeToken, Provider: "RSA"}
+	c := Client{BaseURL: ts.URL}
+
+	cases := []struct {
+		Name          string
+		Input         string
+		ExpectedError bool
+	}{
+		{Name: "Code", Input: "111111\n"},
+		{Name: "NextCode", Input: "123456\n654321\n"},
+		{Name: "WrongNextCode", Input: "123456\n000000\n", ExpectedError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			testutil.WithStdin(t, tc.Input, func() {
+				resp, err := verifyToken(&c, f, "fake_state_token", false)
+				if tc.ExpectedError {
+					assert.Error(t, err)
+				} else {
+					assert.Nil(t, err)
+					assert.Equal(t, "fake_token", resp.SessionToken)
+				}
+			})
+		})
+	}
+}
+
+// getEmailTestServer returns a stand-in for the Okta API verifying an email factor with the code
+// 123456. The magic link is clicked after the given number of polls, or never if it is 0.
+func getEmailTestServer(t *testing.T, clickAfter int) *httptest.Server {
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/authn/factors/email_id/verify", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p["stateToken"])
+
+		switch p["passCode"] {
+		case "":
+			fmt.Fprintf(w, `{"status":"MFA_CHALLENGE","factorResult":"CHALLENGE","expiresAt":"%s"}`,
+				time.Now().Add(time.Minute).UTC().Format(time.RFC3339))
+		case "123456":
+			fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"typed_token"}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	mux.HandleFunc("/api/v1/authn", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p["stateToken"])
+
+		polls++
+		if clickAfter == 0 || polls < clickAfter {
+			fmt.Fprint(w, `{"status":"MFA_CHALLENGE","stateToken":"fake_state_token"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"link_token"}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestGetTransaction(t *testing.T) {
+	ts := getEmailTestServer(t, 1)
+	defer ts.Close()
+
+	c := Client{BaseURL: ts.URL}
+	resp, err := c.GetTransaction(&GetTransactionParams{StateToken: "fake_state_token"})
+	assert.Nil(t, err)
+	assert.Equal(t, StatusSuccess, resp.Status)
+	assert.Equal(t, "link_token", resp.SessionToken)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	f := &Factor{ID: "email_id", FactorType: MFATypeEmail}
+
+	t.Run("MagicLink", func(t *testing.T) {
+		ts := getEmailTestServer(t, 3)
+		defer ts.Close()
+
+		c := Client{BaseURL: ts.URL}
+		testutil.WithStdin(t, "", func() {
+			resp, err := verifyEmail(&c, f, "fake_state_token", time.Millisecond, false)
+			assert.Nil(t, err)
+			assert.Equal(t, "link_token", resp.SessionToken)
+		})
+	})
+
+	t.Run("Code", func(t *testing.T) {
+		ts := getEmailTestServer(t, 0)
+		defer ts.Close()
+
+		c := Client{BaseURL: ts.URL}
+		testutil.WithStdin(t, "123456\n", func() {
+			resp, err := verifyEmail(&c, f, "fake_state_token", time.Hour, false)
+			assert.Nil(t, err)
+			assert.Equal(t, "typed_token", resp.SessionToken)
+		})
+	})
+
+	t.Run("NextPrompt", func(t *testing.T) {
+		ts := getEmailTestServer(t, 1)
+		defer ts.Close()
+
+		r, w, err := os.Pipe()
+		assert.Nil(t, err)
+		stdin := os.Stdin
+		os.Stdin = r
+		defer func() { os.Stdin = stdin }()
+
+		// The link is clicked while the code is being read, the line typed afterwards goes to
+		// the next prompt.
+		c := Client{BaseURL: ts.URL}
+		resp, err := verifyEmail(&c, f, "fake_state_token", time.Millisecond, false)
+		assert.Nil(t, err)
+		assert.Equal(t, "link_token", resp.SessionToken)
+
+		_, err = w.WriteString("yes\n")
+		assert.Nil(t, err)
+		w.Close()
+		assert.True(t, confirm("Continue? ", false))
+	})
+}
+
+// getPushTestServer returns a stand-in for the Okta API verifying a push factor, which returns the
+// given factor result after two polls, and a TOTP factor with the code 123456.
+func getPushTestServer(t *testing.T, result string, cancelled *bool) *httptest.Server {
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/authn/factors/push_id/verify", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			fmt.Fprint(w, `{"status":"MFA_CHALLENGE","factorResult":"WAITING"}`)
+			return
+		}
+		switch result {
+		case VerifyFactorStatusSuccess:
+			fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"push_token"}`)
+		default:
+			fmt.Fprintf(w, `{"status":"MFA_CHALLENGE","factorResult":"%s"}`, result)
+		}
+	})
+	mux.HandleFunc("/api/v1/authn/factors/totp_id/verify", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "123456", p["passCode"])
+		fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"totp_token"}`)
+	})
+	mux.HandleFunc("/api/v1/authn/cancel", func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p["stateToken"])
+		*cancelled = true
+		fmt.Fprint(w, `{"status":"UNAUTHENTICATED"}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestCancelTransaction(t *testing.T) {
+	var cancelled bool
+	ts := getPushTestServer(t, VerifyFactorStatusSuccess, &cancelled)
+	defer ts.Close()
+
+	c := Client{BaseURL: ts.URL}
+	err := c.CancelTransaction(&GetTransactionParams{StateToken: "fake_state_token"})
+	assert.Nil(t, err)
+	assert.True(t, cancelled)
+}
+
+func TestVerifyPush(t *testing.T) {
+	push := Factor{ID: "push_id", FactorType: MFATypePush}
+	totp := Factor{ID: "totp_id", FactorType: MFATypeTOTP}
+
+	cases := []struct {
+		Name          string
+		Result        string
+		Factors       []Factor
+		PushTimeout   time.Duration
+		Input         string
+		Interrupted   bool
+		ExpectedToken string
+		ExpectedError string
+	}{
+		{
+			Name:          "Approved",
+			Result:        VerifyFactorStatusSuccess,
+			Factors:       []Factor{push},
+			PushTimeout:   time.Minute,
+			ExpectedToken: "push_token",
+		},
+		{
+			Name:          "Rejected",
+			Result:        VerifyFactorStatusRejected,
+			Factors:       []Factor{push, totp},
+			PushTimeout:   time.Minute,
+			ExpectedError: "MFA push was rejected",
+		},
+		{
+			Name:          "TimeoutWithoutTOTP",
+			Result:        VerifyFactorStatusTimeout,
+			Factors:       []Factor{push},
+			PushTimeout:   time.Minute,
+			ExpectedError: "MFA push timed out",
+		},
+		{
+			Name:          "TimeoutFallbackToTOTP",
+			Result:        VerifyFactorStatusTimeout,
+			Factors:       []Factor{push, totp},
+			PushTimeout:   time.Minute,
+			Input:         "123456\n",
+			ExpectedToken: "totp_token",
+		},
+		{
+			Name:          "PushTimeoutFallbackToTOTP",
+			Result:        VerifyFactorStatusSuccess,
+			Factors:       []Factor{push, totp},
+			Input:         "123456\n",
+			ExpectedToken: "totp_token",
+		},
+		{
+			Name:          "Interrupted",
+			Result:        VerifyFactorStatusSuccess,
+			Factors:       []Factor{push, totp},
+			PushTimeout:   time.Minute,
+			Interrupted:   true,
+			ExpectedError: "canceled by the user",
+		},
+	}
+
+	defer func(n func() (context.Context, context.CancelFunc)) { notifyInterrupt = n }(notifyInterrupt)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cancelled bool
+			ts := getPushTestServer(t, tc.Result, &cancelled)
+			defer ts.Close()
+
+			notifyInterrupt = func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				if tc.Interrupted {
+					cancel()
+				}
+				return ctx, cancel
+			}
+
+			c := Client{BaseURL: ts.URL}
+			testutil.WithStdin(t, tc.Input, func() {
+				resp, err := verifyPush(&c, &push, "fake_state_token", &mfaOptions{
+					Factors:     tc.Factors,
+					PushTimeout: tc.PushTimeout,
+					Interval:    time.Millisecond,
+				})
+				if tc.ExpectedError != "" {
+					assert.EqualError(t, err, tc.ExpectedError)
+				} else {
+					assert.Nil(t, err)
+					assert.Equal(t, tc.ExpectedToken, resp.SessionToken)
+				}
+				// The transaction is cancelled if the user interrupts clisso.
+				assert.Equal(t, tc.Interrupted, cancelled)
+			})
+		})
+	}
+}
+
+func TestDeviceTokenSent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/authn":
+			var p GetSessionTokenParams
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+			assert.Equal(t, "fake_device_token", p.Context.DeviceToken)
+			fmt.Fprint(w, `{"status":"MFA_REQUIRED","stateToken":"fake_state_token"}`)
+		case "/api/v1/authn/factors/fake_id/verify":
+			assert.Equal(t, "true", r.URL.Query().Get("rememberDevice"))
+			fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"fake_token"}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c := Client{BaseURL: ts.URL, RememberDevice: true}
+	_, err := c.GetSessionToken(&GetSessionTokenParams{
+		Username: "test",
+		Password: "test",
+		Context:  &AuthContext{DeviceToken: "fake_device_token"},
+	})
+	assert.Nil(t, err)
+
+	_, err = c.VerifyFactor(&VerifyFactorParams{FactorID: "fake_id", StateToken: "fake_state_token", PassCode: "123456"})
+	assert.Nil(t, err)
+}
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errorCode":"E0000080","errorSummary":"The password does not meet the complexity requirements of the current password policy.",`+
+			`"errorId":"oae123","errorCauses":[{"errorSummary":"Password requirements were not met."},{"errorSummary":"Password cannot be your current password"}]}`)
+	}))
+	defer ts.Close()
+
+	c := Client{BaseURL: ts.URL}
+	_, err := c.ChangePassword(&ChangePasswordParams{StateToken: "fake_state_token", OldPassword: "old", NewPassword: "new"})
+
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Equal(t, ErrorCodePasswordPolicy, apiErr.ErrorCode)
+	assert.Equal(t, "The password does not meet the complexity requirements of the current password policy.: "+
+		"Password requirements were not met.; Password cannot be your current password (E0000080)", apiErr.Error())
+}
+
+func TestHTTPErrorWithoutBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	c := Client{BaseURL: ts.URL}
+	_, err := c.GetSessionToken(&GetSessionTokenParams{Username: "test", Password: "test"})
+	assert.EqualError(t, err, "doing HTTP request: 502 Bad Gateway")
+}
+
+func TestSendError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	// The request fails without a response, which must not cause a panic.
+	c := Client{BaseURL: ts.URL}
+	_, err := c.GetSessionToken(&GetSessionTokenParams{Username: "test", Password: "test"})
+	assert.Error(t, err)
+}
+
+func TestLaunchApp(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/meta", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><meta http-equiv="Refresh" content="0; url=/app/js"></head></html>`)
+	})
+	mux.HandleFunc("/app/js", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><script>window.location.replace('\x2Fapp\x2Fform');</script></html>`)
+	})
+	mux.HandleFunc("/app/form", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body onload="document.forms[0].submit()"><form method="POST" action="/app/saml">`+
+			`<input type="hidden" name="fromURI" value="/app"></form></body></html>`)
+	})
+	mux.HandleFunc("/app/saml", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/app", r.FormValue("fromURI"))
+		fmt.Fprint(w, `<html><form id="appForm"><input name="SAMLResponse" value="fake_assertion"></form></html>`)
+	})
+	mux.HandleFunc("/app/step-up", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><script>var stateToken = 'fake\x2Dstate\x2Dtoken';</script></html>`)
+	})
+	mux.HandleFunc("/app/error", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title> Access denied </title></head></html>`)
+	})
+	mux.HandleFunc("/app/loop", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><meta http-equiv="refresh" content="0;url=/app/loop"></head></html>`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	// Interstitial pages are followed
+	saml, err := c.LaunchApp(&LaunchAppParams{URL: ts.URL + "/app/meta"})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", *saml)
+
+	// Step-up is reported with the state token
+	_, err = c.LaunchApp(&LaunchAppParams{URL: ts.URL + "/app/step-up"})
+	var stepUp *StepUpError
+	assert.True(t, errors.As(err, &stepUp))
+	assert.Equal(t, "fake-state-token", stepUp.StateToken)
+
+	// Pages without an assertion are named
+	_, err = c.LaunchApp(&LaunchAppParams{URL: ts.URL + "/app/error"})
+	var launchErr *LaunchAppError
+	assert.True(t, errors.As(err, &launchErr))
+	assert.Equal(t, "Access denied", launchErr.Title)
+	assert.EqualError(t, err, fmt.Sprintf(`no SAML assertion returned, got page "Access denied" at %s/app/error`, ts.URL))
+
+	_, err = c.LaunchApp(&LaunchAppParams{URL: ts.URL + "/app/loop"})
+	assert.EqualError(t, err, fmt.Sprintf("no SAML assertion returned, got page %s/app/loop", ts.URL))
+}
@@ -0,0 +1,56 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/allcloud-io/clisso/tokencache"
+)
+
+// cachedDeviceToken is the device token identifying this machine to Okta.
+type cachedDeviceToken struct {
+	DeviceToken string `json:"deviceToken"`
+}
+
+// deviceTokenCacheKey returns the cache key of the device token for the given provider.
+func deviceTokenCacheKey(provider string) string {
+	return "okta-device-token:" + provider
+}
+
+// deviceToken returns the device token of this machine for the given provider. A new token is
+// generated the first time.
+func deviceToken(provider string) (string, error) {
+	var dt cachedDeviceToken
+	ok, err := tokencache.Load(deviceTokenCacheKey(provider), &dt)
+	if err != nil {
+		return "", fmt.Errorf("reading device token: %v", err)
+	}
+	if ok && dt.DeviceToken != "" {
+		return dt.DeviceToken, nil
+	}
+
+	return RotateDeviceToken(provider)
+}
+
+// RotateDeviceToken replaces the device token of this machine for the given provider with a new
+// one and returns it. Okta no longer recognizes the machine as a remembered device afterwards.
+func RotateDeviceToken(provider string) (string, error) {
+	// Okta accepts device tokens of up to 32 characters.
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating device token: %v", err)
+	}
+	token := hex.EncodeToString(b)
+
+	if err := tokencache.Save(deviceTokenCacheKey(provider), &cachedDeviceToken{DeviceToken: token}); err != nil {
+		return "", fmt.Errorf("saving device token: %v", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,39 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"testing"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceToken(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	token, err := deviceToken("okta")
+	assert.Nil(t, err)
+	assert.Len(t, token, 32)
+
+	// The token is stable.
+	again, err := deviceToken("okta")
+	assert.Nil(t, err)
+	assert.Equal(t, token, again)
+
+	// Each provider has its own token.
+	other, err := deviceToken("okta-other")
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, other)
+
+	// Rotating replaces the token.
+	rotated, err := RotateDeviceToken("okta")
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, rotated)
+	again, err = deviceToken("okta")
+	assert.Nil(t, err)
+	assert.Equal(t, rotated, again)
+}
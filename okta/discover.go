@@ -0,0 +1,119 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+)
+
+// AppNameAWS is the name of the AWS Account Federation app in the Okta Integration Network.
+const AppNameAWS = "amazon_aws"
+
+// AWSAppLinks logs in to Okta using the given provider and returns the links to the AWS apps
+// assigned to the user. The link URLs can be used as the url of clisso apps. The cached session is
+// reused if it is still valid.
+func AWSAppLinks(provider string, interactive bool) ([]AppLink, error) {
+	p, err := config.GetOktaProvider(provider)
+	if err != nil {
+		return nil, fmt.Errorf("reading provider config: %v", err)
+	}
+
+	c, err := NewClient(p.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("initializing Okta client: %v", err)
+	}
+
+	links, err := awsAppLinks(c, provider, p, interactive)
+	if err != nil {
+		return nil, fmt.Errorf("getting app links: %v", err)
+	}
+
+	return links, nil
+}
+
+func awsAppLinks(c *Client, provider string, p *config.OktaProviderConfig, interactive bool) ([]AppLink, error) {
+	s := spinner.New(interactive)
+	key := sessionCacheKey(provider, p.BaseURL)
+
+	s.Start()
+	links, err := getAppLinks(c, key)
+	s.Stop()
+	if err != nil {
+		log.WithError(err).Debug("Couldn't use cached Okta session, logging in again")
+
+		if p.AuthFlow == AuthFlowDevice {
+			// There is no app to open a session for, use the access token instead
+			_, t, err := authorizeDevice(p, interactive)
+			if err != nil {
+				return nil, fmt.Errorf("authorizing device: %v", err)
+			}
+			c.AccessToken = t.AccessToken
+			return filterAWS(c.GetAppLinks())
+		}
+
+		st, err := login(c, provider, p, p.BaseURL+"/", interactive)
+		if err != nil {
+			return nil, err
+		}
+
+		// The Identity Engine flow already opened a session
+		if st != "" {
+			s.Start()
+			err = c.OpenSession(st)
+			s.Stop()
+			if err != nil {
+				return nil, fmt.Errorf("opening session: %v", err)
+			}
+		}
+		saveSession(c, key)
+
+		s.Start()
+		links, err = c.GetAppLinks()
+		s.Stop()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return filterAWS(links, nil)
+}
+
+// filterAWS returns the links to AWS apps, passing through an error getting the links.
+func filterAWS(links []AppLink, err error) ([]AppLink, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	var aws []AppLink
+	for _, l := range links {
+		if l.AppName == AppNameAWS {
+			aws = append(aws, l)
+		}
+	}
+
+	return aws, nil
+}
+
+// getAppLinks returns the app links of the user using the cached session. A session rejected by
+// Okta is removed from the cache.
+func getAppLinks(c *Client, key string) ([]AppLink, error) {
+	if !restoreSession(c, key) {
+		return nil, errors.New("no cached session")
+	}
+
+	links, err := c.GetAppLinks()
+	if err != nil {
+		deleteSession(key)
+		return nil, err
+	}
+
+	return links, nil
+}
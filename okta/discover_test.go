@@ -0,0 +1,84 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/tokencache"
+	"github.com/stretchr/testify/assert"
+)
+
+// getAppLinksTestServer returns a stand-in for Okta which opens a session for the session token
+// fake_token and returns the app links of the session.
+func getAppLinksTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/sessionCookieRedirect", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fake_token", r.URL.Query().Get("token"))
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "fake_sid", Path: "/"})
+		http.Redirect(w, r, r.URL.Query().Get("redirectUrl"), http.StatusFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html></html>`)
+	})
+	mux.HandleFunc("/api/v1/sessions/me", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":"fake_sid","status":"ACTIVE","expiresAt":"%s"}`,
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	})
+	mux.HandleFunc("/api/v1/users/me/appLinks", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("sid"); err != nil || cookie.Value != "fake_sid" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"id":"1","label":"AWS Prod","linkUrl":"https://example.okta.com/home/amazon_aws/0oa1/272","appName":"amazon_aws"},
+			{"id":"2","label":"Slack","linkUrl":"https://example.okta.com/home/slack/0oa2/123","appName":"slack"},
+			{"id":"3","label":"AWS Dev","linkUrl":"https://example.okta.com/home/amazon_aws/0oa3/272","appName":"amazon_aws"}
+		]`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestOpenSession(t *testing.T) {
+	ts := getAppLinksTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	assert.Nil(t, c.OpenSession("fake_token"))
+	assert.Equal(t, "fake_sid", c.SessionID())
+
+	links, err := c.GetAppLinks()
+	assert.Nil(t, err)
+	assert.Len(t, links, 3)
+	assert.Equal(t, "AWS Prod", links[0].Label)
+	assert.Equal(t, "amazon_aws", links[0].AppName)
+}
+
+func TestAWSAppLinksCachedSession(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	ts := getAppLinksTestServer(t)
+	defer ts.Close()
+
+	p := &config.OktaProviderConfig{BaseURL: ts.URL}
+	assert.Nil(t, tokencache.Save(sessionCacheKey("okta", ts.URL), &cachedSession{SessionID: "fake_sid"}))
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	links, err := awsAppLinks(c, "okta", p, false)
+	assert.Nil(t, err)
+	assert.Len(t, links, 2)
+	assert.Equal(t, "https://example.okta.com/home/amazon_aws/0oa1/272", links[0].LinkURL)
+	assert.Equal(t, "https://example.okta.com/home/amazon_aws/0oa3/272", links[1].LinkURL)
+}
@@ -6,211 +6,872 @@
 package okta
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/allcloud-io/clisso/aws"
 	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
 	"github.com/allcloud-io/clisso/keychain"
 	"github.com/allcloud-io/clisso/log"
-	"github.com/allcloud-io/clisso/saml"
 	"github.com/allcloud-io/clisso/spinner"
 	"github.com/icza/gog"
+	"golang.org/x/term"
 )
 
 const (
-	MFATypePush = "push"
-	MFATypeTOTP = "token:software:totp"
+	MFATypePush  = "push"
+	MFATypeTOTP  = "token:software:totp"
+	MFATypeSMS   = "sms"
+	MFATypeCall  = "call"
+	MFATypeEmail = "email"
+	// MFATypeHardwareToken is a hardware token generating one-time passwords, e.g. a YubiKey.
+	MFATypeHardwareToken = "token:hardware"
+	// MFATypeToken is a token of a third-party provider, e.g. RSA SecurID or Symantec VIP.
+	MFATypeToken = "token"
 
-	VerifyFactorStatusSuccess = "SUCCESS"
-	VerifyFactorStatusWaiting = "WAITING"
+	VerifyFactorStatusSuccess  = "SUCCESS"
+	VerifyFactorStatusWaiting  = "WAITING"
+	VerifyFactorStatusRejected = "REJECTED"
+	VerifyFactorStatusTimeout  = "TIMEOUT"
+	// VerifyFactorStatusChallenge means the next code of the token is required, e.g. when an RSA
+	// SecurID token is out of sync.
+	VerifyFactorStatusChallenge = "CHALLENGE"
+
+	// MFAPushTimeout represents the number of seconds to wait for a successful push attempt before
+	// falling back to TOTP input, unless configured otherwise for the provider.
+	MFAPushTimeout = 60
+
+	// MFAInterval represents the number of seconds between checks for an accepted push message or
+	// a clicked email link, unless configured otherwise for the provider.
+	MFAInterval = 2
 )
 
 var (
-	keyChain = keychain.DefaultKeychain{}
+	keyChain keychain.Keychain = keychain.DefaultKeychain{}
+
+	// readPassword reads a password from the terminal without echoing it. Replaced in tests.
+	readPassword = func() ([]byte, error) {
+		return term.ReadPassword(int(syscall.Stdin))
+	}
+
+	// resendInterval is the time the user has to wait before a new SMS or voice call code can be
+	// requested.
+	resendInterval = 30 * time.Second
+
+	// notifyInterrupt returns a context which is done when the user interrupts clisso. Until stop
+	// is called, an interrupt doesn't terminate clisso. Replaced in tests.
+	notifyInterrupt = func() (ctx context.Context, stop context.CancelFunc) {
+		return signal.NotifyContext(context.Background(), os.Interrupt)
+	}
 )
 
-// Get gets temporary credentials for the given app.
-func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool) (*aws.Credentials, error) {
+func init() {
+	idp.Register("okta", Provider{})
+}
+
+// Provider implements idp.Provider for Okta.
+type Provider struct{}
+
+// Assertion logs in to Okta and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
 	log.WithFields(log.Fields{
-		"app":         app,
-		"provider":    provider,
-		"pArn":        pArn,
-		"awsRegion":   awsRegion,
-		"duration":    duration,
-		"interactive": interactive,
-	}).Trace("Getting credentials from Okta")
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from Okta")
+
 	// Get provider config
-	p, err := config.GetOktaProvider(provider)
+	p, err := config.GetOktaProvider(r.Provider)
 	if err != nil {
-		return nil, fmt.Errorf("reading provider config: %v", err)
+		return "", fmt.Errorf("reading provider config: %v", err)
 	}
 
 	// Get app config
-	a, err := config.GetOktaApp(app)
+	a, err := config.GetOktaApp(r.App)
 	if err != nil {
-		return nil, fmt.Errorf("reading config for app %s: %v", app, err)
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
 	}
 
 	// Initialize Okta client
 	c, err := NewClient(p.BaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("initializing Okta client: %v", err)
+		return "", fmt.Errorf("initializing Okta client: %v", err)
 	}
 
-	// Get user credentials
-	user := p.Username
-	if user == "" {
-		// Get credentials from the user
-		fmt.Print("Okta username: ")
-		_, err = fmt.Scanln(&user)
+	// Initialize spinner
+	var s = spinner.New(r.Interactive)
+
+	// Launch Okta app, completing any step-up required by the app's sign-on policy
+	launch := func(st string) (string, error) {
+		s.Start()
+		log.WithFields(log.Fields{
+			"SessionToken": st,
+			"URL":          a.URL,
+		}).Trace("Calling LaunchApp")
+		saml, err := c.LaunchApp(&LaunchAppParams{SessionToken: st, URL: a.URL})
+		s.Stop()
+
+		var stepUp *StepUpError
+		if !errors.As(err, &stepUp) {
+			return derefString(saml), err
+		}
+
+		// Step-up is completed in the current session, so there must be one. Okta also returns
+		// the sign-in page for sessions which are no longer valid.
+		if _, err = c.GetSession(); err != nil {
+			return "", fmt.Errorf("no valid Okta session: %v", err)
+		}
+		st, err = completeStepUp(c, r.Provider, p, stepUp, r.Interactive)
 		if err != nil {
-			return nil, fmt.Errorf("reading username: %v", err)
+			return "", fmt.Errorf("completing step-up authentication: %w", err)
 		}
+		s.Start()
+		saml, err = c.LaunchApp(&LaunchAppParams{SessionToken: st, URL: a.URL})
+		s.Stop()
+		return derefString(saml), err
 	}
 
-	pass, err := keyChain.Get(provider)
+	// Reuse the session of an earlier login if it is still valid
+	key := sessionCacheKey(r.Provider, p.BaseURL)
+	samlAssertion, ok := launchWithCachedSession(c, key, launch)
+	if ok {
+		return samlAssertion, nil
+	}
+
+	st, err := login(c, r.Provider, p, a.URL, r.Interactive)
+	if err != nil {
+		return "", err
+	}
+
+	samlAssertion, err = launch(st)
+	if err != nil {
+		return "", fmt.Errorf("error launching app: %v", err)
+	}
+
+	saveSession(c, key)
+
+	return samlAssertion, nil
+}
+
+// derefString returns the string s points to, or an empty string if s is nil.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// completeStepUp completes the step-up authentication an app requires, such as entering the
+// password again or verifying an MFA factor, in the session of the client. It returns a session
+// token when using the classic authentication API. When using the Identity Engine, the session
+// cookie is updated instead and the returned session token is empty.
+func completeStepUp(c *Client, provider string, p *config.OktaProviderConfig, stepUp *StepUpError, interactive bool) (string, error) {
+	if p.AuthFlow == AuthFlowDevice {
+		return "", errors.New("step-up authentication isn't supported by the device auth flow")
+	}
+
+	user, pass, err := credentials(provider, p, interactive)
+	if err != nil {
+		return "", err
+	}
+
+	if useIDX(c, p) {
+		resp, err := c.Introspect(stepUp.StateToken)
+		if err != nil {
+			return "", fmt.Errorf("starting Identity Engine interaction: %v", err)
+		}
+		pushTimeout, interval := mfaTimings(p)
+		return "", idxRemediate(c, resp, &idxParams{
+			Username:    user,
+			Password:    pass,
+			MFAFactor:   p.MFAFactor,
+			PushTimeout: pushTimeout,
+			Interval:    interval,
+			Interactive: interactive,
+		})
+	}
+
+	resp, err := c.GetTransaction(&GetTransactionParams{StateToken: stepUp.StateToken})
 	if err != nil {
-		return nil, fmt.Errorf("getting key chain: %v", err)
+		return "", fmt.Errorf("getting transaction: %v", err)
 	}
+	if resp.Status == StatusUnauthenticated {
+		// Re-authentication is required
+		resp, err = c.GetSessionToken(&GetSessionTokenParams{
+			Username:   user,
+			Password:   pass,
+			StateToken: stepUp.StateToken,
+		})
+		if err != nil {
+			return "", fmt.Errorf("getting session token: %v", err)
+		}
+	}
+
+	return completeTransaction(c, resp, &transactionParams{
+		Provider:    provider,
+		Password:    pass,
+		Config:      p,
+		Interactive: interactive,
+	})
+}
 
+// login logs in to Okta using the username and password of the provider, verifying an MFA factor
+// if required, and returns a session token. When logging in using the Identity Engine or the device
+// auth flow, the client gets a session cookie instead and the returned session token is empty. startURL is the page the
+// Identity Engine sign-in starts from.
+func login(c *Client, provider string, p *config.OktaProviderConfig, startURL string, interactive bool) (string, error) {
 	// Initialize spinner
 	var s = spinner.New(interactive)
 
+	// The device auth flow doesn't need the password
+	if p.AuthFlow == AuthFlowDevice {
+		return "", deviceLogin(c, p, startURL, interactive)
+	}
+
+	user, pass, err := credentials(provider, p, interactive)
+	if err != nil {
+		return "", err
+	}
+
+	if useIDX(c, p) {
+		pushTimeout, interval := mfaTimings(p)
+		err = idxLogin(c, &idxParams{
+			StartURL:    startURL,
+			Username:    user,
+			Password:    pass,
+			MFAFactor:   p.MFAFactor,
+			PushTimeout: pushTimeout,
+			Interval:    interval,
+			Interactive: interactive,
+		})
+		if err != nil {
+			return "", fmt.Errorf("logging in using the Identity Engine: %w", err)
+		}
+		return "", nil
+	}
+
+	// Identify this machine to Okta, so that MFA can be skipped for a remembered device if the org
+	// policy allows it
+	var authContext *AuthContext
+	if dt, err := deviceToken(provider); err == nil {
+		authContext = &AuthContext{DeviceToken: dt}
+		c.RememberDevice = true
+	} else {
+		log.WithError(err).Warn("Couldn't get device token, Okta won't remember this device")
+	}
+
 	// Get session token
 	s.Start()
 	log.WithFields(log.Fields{
 		"Username": user,
 		// print password only in Trace Log Level
-		"Password": gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"Password": gog.If(log.GetLevel() == log.TraceLevel, pass, "<redacted>"),
 	}).Debug("Calling GetSessionToken")
 	resp, err := c.GetSessionToken(&GetSessionTokenParams{
 		Username: user,
-		Password: string(pass),
+		Password: pass,
+		Context:  authContext,
 	})
 	s.Stop()
 	if err != nil {
-		return nil, fmt.Errorf("getting session token: %v", err)
+		return "", fmt.Errorf("getting session token: %v", err)
 	}
 	log.WithField("Status", resp.Status).Trace("GetSessionToken done")
 
-	var st string
+	return completeTransaction(c, resp, &transactionParams{
+		Provider:    provider,
+		Password:    pass,
+		Config:      p,
+		Interactive: interactive,
+	})
+}
 
-	// TODO Handle multiple MFA devices (allow user to choose)
-	switch resp.Status {
-	case StatusSuccess:
-		st = resp.SessionToken
-	case StatusMFARequired:
-		factor := resp.Embedded.Factors[0]
-		stateToken := resp.StateToken
-		log.WithFields(log.Fields{
-			"factorID":   factor.ID,
-			"factorLink": factor.Links.Verify.Href,
-			"stateToken": stateToken,
-			"factorType": factor.FactorType,
-		}).Debug("MFA required")
-
-		var vfResp *VerifyFactorResponse
-
-		switch factor.FactorType {
-		case MFATypePush:
-			// Okta Verify push notification:
-			// https://developer.okta.com/docs/api/resources/authn/#verify-push-factor
-			// Keep polling authentication transactions with WAITING result until the challenge
-			// completes or expires.
-			if interactive {
-				fmt.Println("Please approve request on Okta Verify app")
-			}
-			s.Start()
-			vfResp, err = c.VerifyFactor(&VerifyFactorParams{
-				FactorID:   factor.ID,
-				StateToken: stateToken,
-			})
+// credentials returns the username and password of the provider, asking for the username unless
+// configured.
+func credentials(provider string, p *config.OktaProviderConfig, interactive bool) (string, string, error) {
+	user := p.Username
+	if user == "" {
+		// Get credentials from the user
+		var err error
+		user, err = prompt.Ask("Okta username: ", interactive)
+		if err != nil {
+			return "", "", fmt.Errorf("reading username: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(provider)
+	if err != nil {
+		return "", "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	return user, string(pass), nil
+}
+
+// useIDX returns true if the provider logs in using the Identity Engine. Unless configured for the
+// provider, this is detected from the org, falling back to the classic authentication API.
+func useIDX(c *Client, p *config.OktaProviderConfig) bool {
+	switch p.AuthFlow {
+	case AuthFlowIDX:
+		return true
+	case AuthFlowClassic:
+		return false
+	}
+
+	org, err := c.GetOrganization()
+	if err != nil {
+		log.WithError(err).Debug("Couldn't detect Okta authentication flow, using the classic flow")
+		return false
+	}
+	log.WithField("pipeline", org.Pipeline).Debug("Detected Okta authentication flow")
+
+	return org.Pipeline == AuthFlowIDX
+}
+
+// mfaTimings returns the push timeout and poll interval of the provider, using the defaults
+// unless configured.
+func mfaTimings(p *config.OktaProviderConfig) (time.Duration, time.Duration) {
+	pushTimeout := p.MFAPushTimeout
+	if pushTimeout == 0 {
+		pushTimeout = MFAPushTimeout
+	}
+	interval := p.MFAInterval
+	if interval == 0 {
+		interval = MFAInterval
+	}
+
+	return time.Duration(pushTimeout) * time.Second, time.Duration(interval) * time.Second
+}
+
+// transactionParams represents the parameters for completeTransaction.
+type transactionParams struct {
+	Provider    string
+	Password    string
+	Config      *config.OktaProviderConfig
+	Interactive bool
+}
+
+// completeTransaction handles the states of an authentication transaction until it succeeds, and
+// returns the session token.
+func completeTransaction(c *Client, resp *GetSessionTokenResponse, tp *transactionParams) (string, error) {
+	var err error
+	for {
+		log.WithField("Status", resp.Status).Trace("Handling authentication transaction")
+
+		switch resp.Status {
+		case StatusSuccess:
+			return resp.SessionToken, nil
+		case StatusMFARequired:
+			vfResp, err := verifyMFA(c, resp, tp.Config, tp.Interactive)
 			if err != nil {
-				return nil, fmt.Errorf("verifying MFA: %v", err)
+				return "", fmt.Errorf("verifying MFA: %w", err)
+			}
+
+			switch vfResp.Status {
+			case StatusSuccess:
+				return vfResp.SessionToken, nil
+			case StatusMFARequired, StatusMFAChallenge:
+				// Handle failed MFA verification (verification rejected or timed out)
+				err = fmt.Errorf("MFA verification failed")
+				log.WithField("status", vfResp.Status).WithError(err).Warn("MFA verification failed")
+				return "", err
 			}
 
-			// true if correct answer for Okta Verify has already been shown in CLI
-			// to avoid spamming the user
-			var answerShown bool
-
-			for vfResp.FactorResult == VerifyFactorStatusWaiting {
-				vfResp, err = c.VerifyFactor(&VerifyFactorParams{
-					FactorID:   factor.ID,
-					StateToken: stateToken,
-				})
-				if answer := vfResp.Embedded.Factor.Embedded.Challenge.CorrectAnswer; answer != 0 && !answerShown {
-					s.Stop()
-					fmt.Printf("Okta marked the attempt as unusual, select number '%d' in Okta Verify\n", answer)
-					answerShown = true
+			// Other states, such as a password warning, can follow MFA verification
+			resp, err = c.GetTransaction(&GetTransactionParams{StateToken: resp.StateToken})
+			if err != nil {
+				return "", fmt.Errorf("getting transaction: %v", err)
+			}
+		case StatusPasswordWarn:
+			days := resp.Embedded.Policy.Expiration.PasswordExpireDays
+			if !tp.Interactive || !confirm(fmt.Sprintf("Your Okta password expires in %d day(s). Change it now? [y/N]: ", days), tp.Interactive) {
+				log.Warnf("Your Okta password expires in %d day(s)", days)
+				resp, err = c.SkipTransaction(&GetTransactionParams{StateToken: resp.StateToken})
+				if err != nil {
+					return "", fmt.Errorf("skipping password change: %v", err)
 				}
-				time.Sleep(2 * time.Second)
+				continue
 			}
-			s.Stop()
-		case MFATypeTOTP:
-			fmt.Print("Please enter the OTP from your MFA device: ")
-			var otp string
-			_, err = fmt.Scanln(&otp)
+			resp, err = changePassword(c, resp.StateToken, tp)
 			if err != nil {
-				return nil, fmt.Errorf("reading OTP: %v", err)
+				return "", err
 			}
-
-			s.Start()
-			vfResp, err = c.VerifyFactor(&VerifyFactorParams{
-				FactorID:   factor.ID,
-				PassCode:   otp,
-				StateToken: stateToken,
-			})
-			s.Stop()
+		case StatusPasswordExpired:
+			if !tp.Interactive {
+				return "", errors.New("your Okta password has expired, please change it using the Okta web UI")
+			}
+			fmt.Fprintln(prompt.Output(tp.Interactive), "Your Okta password has expired and must be changed")
+			resp, err = changePassword(c, resp.StateToken, tp)
+			if err != nil {
+				return "", err
+			}
+		case StatusLockedOut:
+			return "", errors.New("your Okta account is locked out, please unlock it using the Okta web UI or contact your Okta administrator")
+		case StatusMFAEnroll:
+			return "", errors.New("no MFA factor enrolled, please enroll one using the Okta web UI")
 		default:
-			return nil, fmt.Errorf("unsupported MFA type '%s'", factor.FactorType)
+			return "", fmt.Errorf("invalid status %s", resp.Status)
 		}
+	}
+}
+
+// verifyMFA selects an MFA factor of a transaction with the status StatusMFARequired and verifies
+// it.
+func verifyMFA(c *Client, resp *GetSessionTokenResponse, p *config.OktaProviderConfig, interactive bool) (*VerifyFactorResponse, error) {
+	factor, err := getFactor(resp.Embedded.Factors, p.MFAFactor, interactive)
+	if err != nil {
+		return nil, err
+	}
+	stateToken := resp.StateToken
+	log.WithFields(log.Fields{
+		"factorID":   factor.ID,
+		"factorLink": factor.Links.Verify.Href,
+		"stateToken": stateToken,
+		"factorType": factor.FactorType,
+	}).Debug("MFA required")
 
+	pushTimeout, interval := mfaTimings(p)
+	return verifyFactor(c, factor, stateToken, &mfaOptions{
+		Factors:     resp.Embedded.Factors,
+		PushTimeout: pushTimeout,
+		Interval:    interval,
+		Interactive: interactive,
+	})
+}
+
+// changePassword prompts the user for a new password, changes it and stores it in the key chain.
+// It returns the state of the transaction after the change.
+func changePassword(c *Client, stateToken string, tp *transactionParams) (*GetSessionTokenResponse, error) {
+	out := prompt.Output(tp.Interactive)
+	for {
+		fmt.Fprint(out, "New Okta password: ")
+		newPass, err := readPassword()
+		fmt.Fprintln(out)
 		if err != nil {
-			return nil, fmt.Errorf("verifying MFA: %v", err)
+			return nil, fmt.Errorf("reading password: %v", err)
+		}
+		fmt.Fprint(out, "Confirm new Okta password: ")
+		confirmation, err := readPassword()
+		fmt.Fprintln(out)
+		if err != nil {
+			return nil, fmt.Errorf("reading password: %v", err)
+		}
+		if string(newPass) != string(confirmation) {
+			fmt.Fprintln(out, "Passwords don't match")
+			continue
 		}
 
-		// Handle failed MFA verification (verification rejected or timed out)
-		if vfResp.Status != VerifyFactorStatusSuccess {
-			err = fmt.Errorf("MFA verification failed")
-			log.WithField("status", vfResp.Status).WithError(err).Warn("MFA verification failed")
-			return nil, fmt.Errorf("MFA verification failed")
+		resp, err := c.ChangePassword(&ChangePasswordParams{
+			StateToken:  stateToken,
+			OldPassword: tp.Password,
+			NewPassword: string(newPass),
+		})
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode == ErrorCodePasswordPolicy {
+			// Let the user try again with a password which complies with the policy
+			fmt.Fprintf(out, "Password change failed: %v\n", apiErr)
+			continue
 		}
+		if err != nil {
+			return nil, fmt.Errorf("changing password: %v", err)
+		}
+
+		if err = keyChain.Set(tp.Provider, newPass); err != nil {
+			log.WithError(err).Warn("Couldn't store the new password in the key chain")
+		} else {
+			log.Debug("Stored the new password in the key chain")
+		}
+		fmt.Fprintln(out, "Password changed")
+
+		return resp, nil
+	}
+}
 
-		st = vfResp.SessionToken
-	default:
-		return nil, fmt.Errorf("invalid status %s", resp.Status)
+// confirm asks the user a yes/no question and returns true if the answer is yes.
+func confirm(question string, interactive bool) bool {
+	answer, err := prompt.Ask(question, interactive)
+	if err != nil {
+		return false
 	}
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
+// mfaOptions represents the options for verifying an MFA factor.
+type mfaOptions struct {
+	// Factors are all the factors enrolled by the user, used to fall back to TOTP when a push
+	// isn't approved in time.
+	Factors []Factor
+	// PushTimeout is the time to wait for a push to be approved.
+	PushTimeout time.Duration
+	// Interval is the time between polls of a transaction waiting for the user.
+	Interval    time.Duration
+	Interactive bool
+}
+
+// verifyFactor verifies the given factor and returns the result of the verification.
+func verifyFactor(c *Client, f *Factor, stateToken string, opts *mfaOptions) (*VerifyFactorResponse, error) {
+	switch f.FactorType {
+	case MFATypePush:
+		return verifyPush(c, f, stateToken, opts)
+	case MFATypeTOTP:
+		return verifyTOTP(c, f, stateToken, opts.Interactive)
+	case MFATypeSMS, MFATypeCall:
+		return verifyChallenge(c, f, stateToken, opts.Interactive)
+	case MFATypeEmail:
+		return verifyEmail(c, f, stateToken, opts.Interval, opts.Interactive)
+	case MFATypeHardwareToken, MFATypeToken:
+		return verifyToken(c, f, stateToken, opts.Interactive)
+	}
+	return nil, fmt.Errorf("unsupported MFA type '%s'", f.FactorType)
+}
+
+// verifyPush sends an Okta Verify push notification and polls the transaction until the push is
+// approved, rejected or times out. If the push isn't approved within the push timeout and the
+// user has a TOTP factor enrolled, the user is prompted for a TOTP code instead. If the user
+// interrupts clisso while waiting, the transaction is cancelled so that the push is discarded, and
+// idp.ErrCanceled is returned.
+// https://developer.okta.com/docs/api/resources/authn/#verify-push-factor
+func verifyPush(c *Client, f *Factor, stateToken string, opts *mfaOptions) (*VerifyFactorResponse, error) {
+	fmt.Fprintln(prompt.Output(opts.Interactive), "Please approve request on Okta Verify app")
+
+	ctx, stop := notifyInterrupt()
+	defer stop()
 
-	// Launch Okta app with session token
+	s := spinner.New(opts.Interactive)
 	s.Start()
-	log.WithFields(log.Fields{
-		"SessionToken": st,
-		"URL":          a.URL,
-	}).Trace("Calling LaunchApp")
-	samlAssertion, err := c.LaunchApp(&LaunchAppParams{SessionToken: st, URL: a.URL})
+	p := &VerifyFactorParams{
+		FactorID:   f.ID,
+		StateToken: stateToken,
+	}
+	vfResp, err := c.VerifyFactor(p)
+	if err != nil {
+		s.Stop()
+		return nil, err
+	}
+
+	// true if correct answer for Okta Verify has already been shown in CLI
+	// to avoid spamming the user
+	var answerShown bool
+
+	deadline := time.Now().Add(opts.PushTimeout)
+	for vfResp.FactorResult == VerifyFactorStatusWaiting && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			s.Stop()
+			return nil, cancelTransaction(c, stateToken)
+		case <-time.After(opts.Interval):
+		}
+		log.Trace("MFAInterval completed, calling VerifyFactor again")
+		vfResp, err = c.VerifyFactor(p)
+		if err != nil {
+			s.Stop()
+			return nil, err
+		}
+		if answer := vfResp.Embedded.Factor.Embedded.Challenge.CorrectAnswer; answer != 0 && !answerShown {
+			s.Stop()
+			fmt.Fprintf(prompt.Output(opts.Interactive), "Okta marked the attempt as unusual, select number '%d' in Okta Verify\n", answer)
+			answerShown = true
+			s.Start()
+		}
+	}
 	s.Stop()
+	// Let an interrupt terminate clisso again while prompting for a TOTP code
+	stop()
+
+	switch vfResp.FactorResult {
+	case VerifyFactorStatusRejected:
+		return nil, errors.New("MFA push was rejected")
+	case VerifyFactorStatusWaiting, VerifyFactorStatusTimeout:
+		for _, totp := range opts.Factors {
+			if totp.FactorType == MFATypeTOTP {
+				fmt.Fprintln(prompt.Output(opts.Interactive), "MFA verification timed out - falling back to TOTP input")
+				return verifyTOTP(c, &totp, stateToken, opts.Interactive)
+			}
+		}
+		return nil, errors.New("MFA push timed out")
+	}
+
+	return vfResp, nil
+}
+
+// verifyTOTP prompts the user for a TOTP code and verifies it.
+func verifyTOTP(c *Client, f *Factor, stateToken string, interactive bool) (*VerifyFactorResponse, error) {
+	fmt.Fprint(prompt.Output(interactive), "Please enter the OTP from your MFA device: ")
+	otp, err := prompt.ReadLine()
 	if err != nil {
-		return nil, fmt.Errorf("error launching app: %v", err)
+		return nil, fmt.Errorf("reading OTP: %v", err)
 	}
 
-	arn, err := saml.Get(*samlAssertion, pArn)
+	s := spinner.New(interactive)
+	s.Start()
+	defer s.Stop()
+	return c.VerifyFactor(&VerifyFactorParams{
+		FactorID:   f.ID,
+		PassCode:   otp,
+		StateToken: stateToken,
+	})
+}
+
+// verifyToken verifies a hardware token or a token of a third-party provider, such as RSA SecurID
+// or Symantec VIP, using a code typed by the user. If the token is out of sync, the user is asked
+// for the next code as well.
+// https://developer.okta.com/docs/reference/api/authn/#verify-token-factor
+func verifyToken(c *Client, f *Factor, stateToken string, interactive bool) (*VerifyFactorResponse, error) {
+	fmt.Fprintf(prompt.Output(interactive), "Please enter the code from your %s: ", tokenName(f))
+	passCode, err := prompt.ReadLine()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("reading code: %v", err)
 	}
 
+	s := spinner.New(interactive)
 	s.Start()
-	creds, err := aws.AssumeSAMLRole(arn.Provider, arn.Role, *samlAssertion, awsRegion, duration)
+	vfResp, err := c.VerifyFactor(&VerifyFactorParams{
+		FactorID:   f.ID,
+		PassCode:   passCode,
+		StateToken: stateToken,
+	})
 	s.Stop()
+	if err != nil || vfResp.FactorResult != VerifyFactorStatusChallenge {
+		return vfResp, err
+	}
 
+	fmt.Fprint(prompt.Output(interactive), "Please wait for the code to change and enter the next code: ")
+	nextPassCode, err := prompt.ReadLine()
 	if err != nil {
-		if err.Error() == aws.ErrDurationExceeded {
-			log.Warn(aws.DurationExceededMessage)
-			s.Start()
-			creds, err = aws.AssumeSAMLRole(arn.Provider, arn.Role, *samlAssertion, awsRegion, 3600)
-			s.Stop()
+		return nil, fmt.Errorf("reading code: %v", err)
+	}
+
+	s.Start()
+	defer s.Stop()
+	return c.VerifyFactor(&VerifyFactorParams{
+		FactorID:     f.ID,
+		PassCode:     passCode,
+		NextPassCode: nextPassCode,
+		StateToken:   stateToken,
+	})
+}
+
+// tokenName returns the name of the token of a factor, as shown to the user.
+func tokenName(f *Factor) string {
+	switch {
+	case f.FactorType == MFATypeHardwareToken:
+		return "hardware token"
+	case strings.EqualFold(f.Provider, "RSA"):
+		return "RSA SecurID token"
+	case strings.EqualFold(f.Provider, "SYMANTEC"):
+		return "Symantec VIP app"
+	}
+	return "token"
+}
+
+// cancelTransaction cancels the transaction after the user interrupted clisso, so that pending
+// challenges such as push notifications are discarded, and returns idp.ErrCanceled.
+func cancelTransaction(c *Client, stateToken string) error {
+	log.Debug("Interrupted, cancelling Okta transaction")
+	if err := c.CancelTransaction(&GetTransactionParams{StateToken: stateToken}); err != nil {
+		log.WithError(err).Warn("Couldn't cancel Okta transaction")
+	}
+	return idp.ErrCanceled
+}
+
+// verifyChallenge verifies an SMS or voice call factor. The first call to VerifyFactor without a
+// passcode makes Okta deliver the code, which the user is then prompted for. The user can request
+// a new code once resendInterval has passed since the last one was sent.
+func verifyChallenge(c *Client, f *Factor, stateToken string, interactive bool) (*VerifyFactorResponse, error) {
+	p := &VerifyFactorParams{
+		FactorID:   f.ID,
+		StateToken: stateToken,
+	}
+	vfResp, err := c.VerifyFactor(p)
+	if err != nil {
+		return nil, fmt.Errorf("sending MFA challenge: %v", err)
+	}
+	if vfResp.Status != StatusMFAChallenge {
+		return vfResp, nil
+	}
+	sent := time.Now()
+	printChallengeSent(f, interactive)
+
+	for {
+		canResend := time.Since(sent) >= resendInterval
+		if canResend {
+			fmt.Fprint(prompt.Output(interactive), "Please enter the code (or 'resend' to get a new one): ")
+		} else {
+			fmt.Fprint(prompt.Output(interactive), "Please enter the code: ")
+		}
+		code, err := prompt.ReadLine()
+		if err != nil {
+			return nil, fmt.Errorf("reading code: %v", err)
+		}
+
+		if code != "resend" {
+			p.PassCode = code
+			return c.VerifyFactor(p)
+		}
+
+		if !canResend {
+			fmt.Fprintf(prompt.Output(interactive), "Please wait %d seconds before requesting a new code\n",
+				int((resendInterval-time.Since(sent)).Seconds()+1))
+			continue
+		}
+		if _, err = c.ResendFactor(p); err != nil {
+			return nil, fmt.Errorf("resending MFA challenge: %v", err)
+		}
+		sent = time.Now()
+		printChallengeSent(f, interactive)
+	}
+}
+
+// printChallengeSent tells the user where the code of an SMS or voice call factor was sent.
+func printChallengeSent(f *Factor, interactive bool) {
+	if f.FactorType == MFATypeCall {
+		fmt.Fprintf(prompt.Output(interactive), "Calling %s to deliver the code\n", f.Profile.PhoneNumber)
+	} else {
+		fmt.Fprintf(prompt.Output(interactive), "A code was sent by SMS to %s\n", f.Profile.PhoneNumber)
+	}
+}
+
+// verifyEmail verifies an email factor. Okta sends an email containing both a code and a magic
+// link. The transaction is polled for the link being clicked while the user is prompted for the
+// code, and whichever happens first completes the verification.
+func verifyEmail(c *Client, f *Factor, stateToken string, interval time.Duration, interactive bool) (*VerifyFactorResponse, error) {
+	p := &VerifyFactorParams{
+		FactorID:   f.ID,
+		StateToken: stateToken,
+	}
+	vfResp, err := c.VerifyFactor(p)
+	if err != nil {
+		return nil, fmt.Errorf("sending MFA challenge: %v", err)
+	}
+	if vfResp.Status != StatusMFAChallenge {
+		return vfResp, nil
+	}
+	expiresAt := vfResp.ExpiresAt
+
+	fmt.Fprintf(prompt.Output(interactive), "An email was sent to %s\n", f.Profile.Email)
+	fmt.Fprint(prompt.Output(interactive), "Please click the link in the email or enter the code: ")
+
+	// The code is read while polling. If the link is clicked first, the line is left for the next
+	// prompt.
+	codes := prompt.NextLine()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for expiresAt.IsZero() || time.Now().Before(expiresAt) {
+		select {
+		case code := <-codes:
+			if code.Err != nil {
+				log.WithError(code.Err).Debug("Could not read code, waiting for the link to be clicked")
+				codes = nil
+				continue
+			}
+			p.PassCode = code.Text
+			return c.VerifyFactor(p)
+		case <-ticker.C:
+			resp, err := c.GetTransaction(&GetTransactionParams{StateToken: stateToken})
+			if err != nil {
+				return nil, fmt.Errorf("polling transaction: %v", err)
+			}
+			if resp.Status == StatusMFAChallenge {
+				continue
+			}
+			// Print a newline since the prompt is still waiting for the code.
+			fmt.Fprintln(prompt.Output(interactive))
+			return &VerifyFactorResponse{
+				ExpiresAt:    resp.ExpiresAt,
+				SessionToken: resp.SessionToken,
+				Status:       resp.Status,
+			}, nil
+		}
+	}
+
+	return nil, errors.New("MFA challenge expired")
+}
+
+// isSupported returns true if clisso can verify the factor.
+func isSupported(f Factor) bool {
+	switch f.FactorType {
+	case MFATypePush, MFATypeTOTP, MFATypeSMS, MFATypeCall, MFATypeEmail, MFATypeHardwareToken, MFATypeToken:
+		return true
+	}
+	return false
+}
+
+// matchesFactor returns true if the factor matches the configured preferred factor, given either
+// as a factor type (e.g. "push") or as a provider and a factor type separated by a colon (e.g.
+// "GOOGLE:token:software:totp").
+func matchesFactor(f Factor, preferred string) bool {
+	return strings.EqualFold(preferred, f.FactorType) ||
+		strings.EqualFold(preferred, f.Provider+":"+f.FactorType)
+}
+
+// getFactor gets a slice of enrolled MFA factors and returns the one to verify. Unsupported
+// factors are skipped. If only a single supported factor is enrolled, it is returned. Otherwise
+// the preferred factor is returned if it is enrolled, or the user is prompted to select one.
+func getFactor(factors []Factor, preferred string, interactive bool) (*Factor, error) {
+	var supported []Factor
+	for _, f := range factors {
+		if !isSupported(f) {
+			log.WithField("factor", f.String()).Debug("Skipping unsupported MFA factor")
+			continue
+		}
+		supported = append(supported, f)
+	}
+
+	if len(supported) == 0 {
+		return nil, errors.New("no supported MFA factor enrolled")
+	}
+
+	if len(supported) == 1 {
+		log.Trace("Only one supported MFA factor enrolled, automatically selecting it.")
+		return &supported[0], nil
+	}
+
+	out := prompt.Output(interactive)
+	if preferred != "" {
+		for _, f := range supported {
+			if matchesFactor(f, preferred) {
+				log.WithField("MFAFactor", preferred).Trace("MFA factor found, automatically selecting it.")
+				return &f, nil
+			}
+		}
+		// If the preferred factor is not enrolled, fall through and let the user select one.
+		fmt.Fprintf(out, "MFA factor %s not found.\n", preferred)
+	}
+
+	var selection int
+	for {
+		for i, f := range supported {
+			fmt.Fprintf(out, "%d. %s\n", i+1, f)
+		}
+
+		input, err := prompt.Ask(fmt.Sprintf("Please choose an MFA factor to authenticate with (1-%d): ", len(supported)), interactive)
+		if err != nil {
+			return nil, fmt.Errorf("reading MFA factor: %v", err)
+		}
+
+		// Verify we got an integer.
+		selection, err = strconv.Atoi(input)
+		if err != nil {
+			fmt.Fprintf(out, "Invalid input '%s'\n", input)
+			continue
+		}
+
+		// Verify selection is within range.
+		if selection < 1 || selection > len(supported) {
+			fmt.Fprintf(out, "Invalid value %d. Valid values: 1-%d\n", selection, len(supported))
+			continue
 		}
+		break
 	}
 
-	return creds, err
+	return &supported[selection-1], nil
 }
@@ -0,0 +1,125 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFactor(t *testing.T) {
+	var factorList = []Factor{
+		{ID: "1", FactorType: "webauthn", Provider: "FIDO"},
+		{ID: "2", FactorType: MFATypePush, Provider: "OKTA"},
+		{ID: "3", FactorType: MFATypeTOTP, Provider: "OKTA"},
+		{ID: "4", FactorType: MFATypeTOTP, Provider: "GOOGLE"},
+	}
+
+	cases := []struct {
+		Name           string
+		Factors        []Factor
+		Preferred      string
+		Input          string
+		ExpectedFactor string
+		ExpectedError  error
+	}{
+		{
+			Name:          "NoFactors",
+			Factors:       []Factor{},
+			ExpectedError: errors.New("no supported MFA factor enrolled"),
+		},
+		{
+			Name:          "OnlyUnsupportedFactors",
+			Factors:       factorList[:1],
+			ExpectedError: errors.New("no supported MFA factor enrolled"),
+		},
+		{
+			Name:           "SingleSupportedFactor",
+			Factors:        factorList[:2],
+			Preferred:      MFATypeTOTP,
+			ExpectedFactor: "2",
+		},
+		{
+			Name:           "PreferredType",
+			Factors:        factorList,
+			Preferred:      MFATypeTOTP,
+			ExpectedFactor: "3",
+		},
+		{
+			Name:           "PreferredProviderAndType",
+			Factors:        factorList,
+			Preferred:      "google:token:software:totp",
+			ExpectedFactor: "4",
+		},
+		{
+			Name:           "Prompt",
+			Factors:        factorList,
+			Input:          "3\n",
+			ExpectedFactor: "4",
+		},
+		{
+			Name:           "PreferredNotFound",
+			Factors:        factorList,
+			Preferred:      "sms",
+			Input:          "0\n1\n",
+			ExpectedFactor: "2",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			testutil.WithStdin(t, c.Input, func() {
+				stdout, _ := testutil.CaptureOutput(t, func() {
+					f, err := getFactor(c.Factors, c.Preferred, false)
+					assert.Equal(t, c.ExpectedError, err)
+					if c.ExpectedFactor != "" {
+						assert.Equal(t, c.ExpectedFactor, f.ID)
+					}
+				})
+				// The factor list and prompt go to stderr when not interactive, since stdout may
+				// carry credentials.
+				assert.Empty(t, stdout)
+			})
+		})
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	defer func(k keychain.Keychain) { keyChain = k }(keyChain)
+	keyChain = testKeychain{"okta-test": []byte("secret")}
+
+	testutil.WithStdin(t, "user\n", func() {
+		var user, pass string
+		stdout, stderr := testutil.CaptureOutput(t, func() {
+			var err error
+			user, pass, err = credentials("okta-test", &config.OktaProviderConfig{}, false)
+			assert.Nil(t, err)
+		})
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "secret", pass)
+		assert.Empty(t, stdout)
+		assert.Equal(t, "Okta username: ", stderr)
+	})
+}
+
+func TestTokenName(t *testing.T) {
+	assert.Equal(t, "hardware token", tokenName(&Factor{FactorType: MFATypeHardwareToken, Provider: "YUBICO"}))
+	assert.Equal(t, "RSA SecurID token", tokenName(&Factor{FactorType: MFATypeToken, Provider: "RSA"}))
+	assert.Equal(t, "Symantec VIP app", tokenName(&Factor{FactorType: MFATypeToken, Provider: "SYMANTEC"}))
+}
+
+func TestFactorString(t *testing.T) {
+	f := Factor{FactorType: MFATypeTOTP, Provider: "GOOGLE"}
+	assert.Equal(t, "GOOGLE token:software:totp", f.String())
+
+	f.Profile.CredentialID = "user@example.com"
+	assert.Equal(t, "GOOGLE token:software:totp (user@example.com)", f.String())
+}
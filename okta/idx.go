@@ -0,0 +1,696 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+)
+
+const (
+	// AuthFlowClassic is the authentication flow using the classic authentication API.
+	AuthFlowClassic = "classic"
+	// AuthFlowIDX is the authentication flow using the Identity Engine interaction API.
+	AuthFlowIDX = "idx"
+	// AuthFlowDevice is the authentication flow using the OAuth 2.0 device authorization grant,
+	// which doesn't require storing the password.
+	AuthFlowDevice = "device"
+
+	// idxContentType is the media type of Identity Engine requests and responses.
+	idxContentType = "application/ion+json; okta-version=1.0.0"
+
+	// Names of Identity Engine remediations.
+	remediationIdentify               = "identify"
+	remediationSelectAuthenticator    = "select-authenticator-authenticate"
+	remediationVerificationData       = "authenticator-verification-data"
+	remediationChallengeAuthenticator = "challenge-authenticator"
+	remediationChallengePoll          = "challenge-poll"
+	remediationSkip                   = "skip"
+
+	// idxMaxSteps is the maximum number of remediations handled before giving up, to avoid looping
+	// forever on unexpected responses.
+	idxMaxSteps = 20
+)
+
+var (
+	stateTokenRegexp = regexp.MustCompile(`var stateToken = '([^']+)'`)
+	jsEscapeRegexp   = regexp.MustCompile(`\\x([0-9A-Fa-f]{2})`)
+)
+
+// Organization represents the public information about an Okta org.
+type Organization struct {
+	ID       string `json:"id"`
+	Pipeline string `json:"pipeline"`
+}
+
+// GetOrganization returns the public information about the Okta org, which includes whether it
+// uses the Identity Engine.
+func (c *Client) GetOrganization() (*Organization, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/.well-known/okta-organization", nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	data, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
+	}
+
+	var resp Organization
+	err = json.Unmarshal([]byte(data), &resp)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+
+	return &resp, nil
+}
+
+// IDXResponse represents the state of an Identity Engine interaction.
+type IDXResponse struct {
+	StateHandle string    `json:"stateHandle"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Remediation struct {
+		Value []Remediation `json:"value"`
+	} `json:"remediation"`
+	CurrentAuthenticator struct {
+		Value IDXAuthenticator `json:"value"`
+	} `json:"currentAuthenticator"`
+	CurrentAuthenticatorEnrollment struct {
+		Value IDXAuthenticator `json:"value"`
+	} `json:"currentAuthenticatorEnrollment"`
+	Authenticators struct {
+		Value []IDXAuthenticator `json:"value"`
+	} `json:"authenticators"`
+	Messages struct {
+		Value []IDXMessage `json:"value"`
+	} `json:"messages"`
+	Success *struct {
+		Name string `json:"name"`
+		Href string `json:"href"`
+	} `json:"success"`
+	// Cancel is the action cancelling the interaction.
+	Cancel *Remediation `json:"cancel"`
+}
+
+// Remediation represents a step the user can take to advance an Identity Engine interaction.
+type Remediation struct {
+	Name    string      `json:"name"`
+	Href    string      `json:"href"`
+	Method  string      `json:"method"`
+	Refresh int         `json:"refresh"`
+	Value   []FormValue `json:"value"`
+}
+
+// FormValue represents a field of a remediation form.
+type FormValue struct {
+	Name     string          `json:"name"`
+	Label    string          `json:"label"`
+	Required bool            `json:"required"`
+	Value    json.RawMessage `json:"value"`
+	Form     *struct {
+		Value []FormValue `json:"value"`
+	} `json:"form"`
+	Options []struct {
+		Label string          `json:"label"`
+		Value json.RawMessage `json:"value"`
+	} `json:"options"`
+}
+
+// IDXAuthenticator represents an authenticator taking part in an Identity Engine interaction.
+type IDXAuthenticator struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Key         string `json:"key"`
+	DisplayName string `json:"displayName"`
+	Profile     struct {
+		Email string `json:"email"`
+	} `json:"profile"`
+	Methods []struct {
+		Type string `json:"type"`
+	} `json:"methods"`
+	ContextualData struct {
+		CorrectAnswer string `json:"correctAnswer"`
+	} `json:"contextualData"`
+}
+
+// IDXMessage represents a message returned by the Identity Engine, such as an error.
+type IDXMessage struct {
+	Message string `json:"message"`
+	Class   string `json:"class"`
+}
+
+// remediation returns the remediation with the given name, or nil if there is none.
+func (r *IDXResponse) remediation(name string) *Remediation {
+	for i := range r.Remediation.Value {
+		if r.Remediation.Value[i].Name == name {
+			return &r.Remediation.Value[i]
+		}
+	}
+	return nil
+}
+
+// remediationNames returns the names of the remediations offered.
+func (r *IDXResponse) remediationNames() []string {
+	var names []string
+	for _, rem := range r.Remediation.Value {
+		names = append(names, rem.Name)
+	}
+	return names
+}
+
+// err returns the error messages of the response as an error, or nil if there are none.
+func (r *IDXResponse) err() error {
+	var msgs []string
+	for _, m := range r.Messages.Value {
+		if m.Class == "ERROR" {
+			msgs = append(msgs, m.Message)
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// field returns the form field with the given name, or nil if there is none.
+func (rem *Remediation) field(name string) *FormValue {
+	for i := range rem.Value {
+		if rem.Value[i].Name == name {
+			return &rem.Value[i]
+		}
+	}
+	return nil
+}
+
+// GetStateToken loads a page which requires signing in, such as an app embed link, and returns the
+// state token of the sign-in page Okta serves. The state token starts an Identity Engine
+// interaction.
+func (c *Client) GetStateToken(url string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading HTTP response: %v", err)
+	}
+
+	m := stateTokenRegexp.FindSubmatch(body)
+	if m == nil {
+		return "", errors.New("no state token found on sign-in page")
+	}
+
+	// The state token is embedded in JavaScript, with some characters escaped
+	return unescapeJS(string(m[1])), nil
+}
+
+// Introspect starts an Identity Engine interaction using a state token.
+func (c *Client) Introspect(stateToken string) (*IDXResponse, error) {
+	return c.idxRequest(c.BaseURL+"/idp/idx/introspect", map[string]interface{}{"stateToken": stateToken})
+}
+
+// Remediate submits a remediation form with the given values. The state handle is added by
+// Remediate.
+func (c *Client) Remediate(rem *Remediation, stateHandle string, values map[string]interface{}) (*IDXResponse, error) {
+	body := map[string]interface{}{"stateHandle": stateHandle}
+	for k, v := range values {
+		body[k] = v
+	}
+	return c.idxRequest(rem.Href, body)
+}
+
+// idxRequest posts a body to an Identity Engine endpoint. Error messages returned by the endpoint
+// are returned as the error.
+func (c *Client) idxRequest(url string, body interface{}) (*IDXResponse, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encoding body: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", idxContentType)
+	req.Header.Set("Content-Type", idxContentType)
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"url":    resp.Request.URL,
+		"method": resp.Request.Method,
+	}).Trace("HTTP request sent")
+
+	var idx IDXResponse
+	if err = json.NewDecoder(resp.Body).Decode(&idx); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New(resp.Status)
+		}
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+	if err = idx.err(); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	return &idx, nil
+}
+
+// idxParams represents the parameters for idxLogin.
+type idxParams struct {
+	// StartURL is a page requiring a session, which redirects to the sign-in page.
+	StartURL string
+	Username string
+	Password string
+	// MFAFactor is the preferred MFA factor, as used with the classic authentication API.
+	MFAFactor   string
+	PushTimeout time.Duration
+	Interval    time.Duration
+	Interactive bool
+}
+
+// idxLogin logs in using the Identity Engine. It handles remediations until the interaction
+// succeeds, and then opens a session, which the client uses for later requests.
+func idxLogin(c *Client, p *idxParams) error {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	stateToken, err := c.GetStateToken(p.StartURL)
+	if err == nil {
+		var resp *IDXResponse
+		resp, err = c.Introspect(stateToken)
+		s.Stop()
+		if err == nil {
+			return idxRemediate(c, resp, p)
+		}
+	}
+	s.Stop()
+
+	return fmt.Errorf("starting Identity Engine interaction: %v", err)
+}
+
+// idxRemediate handles the remediations of an interaction until it succeeds.
+func idxRemediate(c *Client, resp *IDXResponse, p *idxParams) error {
+	s := spinner.New(p.Interactive)
+
+	// The method of the selected authenticator, needed again if Okta asks to confirm it
+	var methodType string
+	var err error
+
+	for i := 0; i < idxMaxSteps; i++ {
+		if resp.Success != nil {
+			// Following the success redirect sets the session cookie
+			s.Start()
+			err = c.OpenSessionURL(resp.Success.Href)
+			s.Stop()
+			return err
+		}
+
+		log.WithField("remediations", resp.remediationNames()).Debug("Handling Identity Engine remediations")
+
+		var rem *Remediation
+		var values map[string]interface{}
+
+		switch {
+		case resp.remediation(remediationIdentify) != nil:
+			rem = resp.remediation(remediationIdentify)
+			values = map[string]interface{}{"identifier": p.Username}
+			if rem.field("credentials") != nil {
+				values["credentials"] = map[string]string{"passcode": p.Password}
+			}
+		case resp.remediation(remediationChallengeAuthenticator) != nil:
+			// Okta offers both a code and polling for some authenticators, such as email, so the
+			// code is checked first.
+			rem = resp.remediation(remediationChallengeAuthenticator)
+			enrollment := resp.CurrentAuthenticatorEnrollment.Value
+			if poll := resp.remediation(remediationChallengePoll); poll != nil && enrollment.Type == "email" {
+				resp, err = idxEmail(c, resp, rem, poll, p)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			passcode := p.Password
+			if enrollment.Type != "password" {
+				fmt.Fprintf(prompt.Output(p.Interactive), "Please enter the code from %s: ", enrollment.DisplayName)
+				if passcode, err = prompt.ReadLine(); err != nil {
+					return fmt.Errorf("reading code: %v", err)
+				}
+			}
+			values = map[string]interface{}{"credentials": map[string]string{"passcode": passcode}}
+		case resp.remediation(remediationChallengePoll) != nil:
+			rem = resp.remediation(remediationChallengePoll)
+			var next *IDXResponse
+			next, err = idxPoll(c, resp, rem, p)
+			if err != errPushTimeout {
+				if err != nil {
+					return err
+				}
+				resp = next
+				continue
+			}
+
+			// Fall back to a TOTP authenticator like verifyPush does
+			resp = next
+			var id string
+			rem, id, methodType = idxTOTPFallback(resp)
+			if rem == nil {
+				return errors.New("MFA push timed out")
+			}
+			fmt.Fprintln(prompt.Output(p.Interactive), "MFA verification timed out - falling back to TOTP input")
+			values = map[string]interface{}{"authenticator": map[string]string{"id": id, "methodType": methodType}}
+		case resp.remediation(remediationVerificationData) != nil:
+			rem = resp.remediation(remediationVerificationData)
+			id, mt, err := selectAuthenticator(resp, rem, p.MFAFactor, p.Interactive)
+			if err != nil {
+				return err
+			}
+			if methodType != "" {
+				mt = methodType
+			}
+			values = map[string]interface{}{"authenticator": map[string]string{"id": id, "methodType": mt}}
+		case resp.remediation(remediationSelectAuthenticator) != nil:
+			rem = resp.remediation(remediationSelectAuthenticator)
+			var id string
+			id, methodType, err = selectAuthenticator(resp, rem, p.MFAFactor, p.Interactive)
+			if err != nil {
+				return err
+			}
+			values = map[string]interface{}{"authenticator": map[string]string{"id": id, "methodType": methodType}}
+		case resp.remediation(remediationSkip) != nil:
+			rem = resp.remediation(remediationSkip)
+		default:
+			return fmt.Errorf("unsupported Okta remediation(s): %s", strings.Join(resp.remediationNames(), ", "))
+		}
+
+		log.WithField("remediation", rem.Name).Trace("Submitting remediation")
+		s.Start()
+		resp, err = c.Remediate(rem, resp.StateHandle, values)
+		s.Stop()
+		if err != nil {
+			return fmt.Errorf("%s: %v", rem.Name, err)
+		}
+	}
+
+	return errors.New("too many Identity Engine remediations")
+}
+
+// errPushTimeout is returned by idxPoll if the push isn't approved within the push timeout.
+var errPushTimeout = errors.New("MFA push timed out")
+
+// idxPoll polls an interaction waiting for an Okta Verify push to be approved. If the push isn't
+// approved within the push timeout, the last response is returned with errPushTimeout, so that
+// another authenticator can be selected. If the user interrupts clisso while waiting, the
+// interaction is cancelled and idp.ErrCanceled is returned.
+func idxPoll(c *Client, resp *IDXResponse, rem *Remediation, p *idxParams) (*IDXResponse, error) {
+	fmt.Fprintln(prompt.Output(p.Interactive), "Please approve request on Okta Verify app")
+
+	ctx, stop := notifyInterrupt()
+	defer stop()
+
+	// true if correct answer for Okta Verify has already been shown in CLI
+	var answerShown bool
+
+	s := spinner.New(p.Interactive)
+	s.Start()
+	defer s.Stop()
+
+	deadline := time.Now().Add(p.PushTimeout)
+	for time.Now().Before(deadline) {
+		if answer := resp.CurrentAuthenticator.Value.ContextualData.CorrectAnswer; answer != "" && !answerShown {
+			s.Stop()
+			fmt.Fprintf(prompt.Output(p.Interactive), "Okta marked the attempt as unusual, select number '%s' in Okta Verify\n", answer)
+			answerShown = true
+			s.Start()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, idxCancel(c, resp)
+		case <-time.After(p.Interval):
+		}
+		next, err := c.Remediate(rem, resp.StateHandle, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", rem.Name, err)
+		}
+		if poll := next.remediation(remediationChallengePoll); poll == nil || next.Success != nil {
+			return next, nil
+		}
+		resp = next
+	}
+
+	return resp, errPushTimeout
+}
+
+// idxCancel cancels the interaction after the user interrupted clisso, so that the pending push
+// is discarded, and returns idp.ErrCanceled.
+func idxCancel(c *Client, resp *IDXResponse) error {
+	log.Debug("Interrupted, cancelling Identity Engine interaction")
+	if resp.Cancel == nil {
+		log.Warn("Couldn't cancel Identity Engine interaction, no cancel action offered")
+		return idp.ErrCanceled
+	}
+	if _, err := c.Remediate(resp.Cancel, resp.StateHandle, nil); err != nil {
+		log.WithError(err).Warn("Couldn't cancel Identity Engine interaction")
+	}
+	return idp.ErrCanceled
+}
+
+// idxTOTPFallback returns the remediation selecting another authenticator, and the ID and method
+// type of a TOTP authenticator it offers. The remediation is nil if no TOTP authenticator is
+// offered.
+func idxTOTPFallback(resp *IDXResponse) (*Remediation, string, string) {
+	rem := resp.remediation(remediationSelectAuthenticator)
+	if rem == nil {
+		return nil, "", ""
+	}
+	factors, methods, _ := idxFactors(resp, rem)
+	for _, f := range factors {
+		if f.FactorType == MFATypeTOTP {
+			return rem, strings.TrimSuffix(f.ID, ":"+methods[f.ID]), methods[f.ID]
+		}
+	}
+	return nil, "", ""
+}
+
+// idxEmail verifies an email authenticator. Like verifyEmail, the interaction is polled for the
+// link in the email being clicked while the user is prompted for the code, and whichever happens
+// first completes the challenge.
+func idxEmail(c *Client, resp *IDXResponse, rem, poll *Remediation, p *idxParams) (*IDXResponse, error) {
+	if email := resp.CurrentAuthenticatorEnrollment.Value.Profile.Email; email != "" {
+		fmt.Fprintf(prompt.Output(p.Interactive), "An email was sent to %s\n", email)
+	}
+	fmt.Fprint(prompt.Output(p.Interactive), "Please click the link in the email or enter the code: ")
+
+	// The code is read while polling. If the link is clicked first, the line is left for the next
+	// prompt.
+	codes := prompt.NextLine()
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	expiresAt := resp.ExpiresAt
+	for expiresAt.IsZero() || time.Now().Before(expiresAt) {
+		select {
+		case code := <-codes:
+			if code.Err != nil {
+				log.WithError(code.Err).Debug("Could not read code, waiting for the link to be clicked")
+				codes = nil
+				continue
+			}
+			values := map[string]interface{}{"credentials": map[string]string{"passcode": code.Text}}
+			next, err := c.Remediate(rem, resp.StateHandle, values)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", rem.Name, err)
+			}
+			return next, nil
+		case <-ticker.C:
+			next, err := c.Remediate(poll, resp.StateHandle, nil)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", poll.Name, err)
+			}
+			if next.remediation(remediationChallengePoll) != nil && next.Success == nil {
+				continue
+			}
+			// Print a newline since the prompt is still waiting for the code.
+			fmt.Fprintln(prompt.Output(p.Interactive))
+			return next, nil
+		}
+	}
+
+	return nil, errors.New("MFA challenge expired")
+}
+
+// idxMethodTypes maps Identity Engine authenticator method types to the factor types of the
+// classic authentication API, so that factors are selected the same way for both.
+var idxMethodTypes = map[string]string{
+	"push":  MFATypePush,
+	"totp":  MFATypeTOTP,
+	"otp":   MFATypeTOTP,
+	"sms":   MFATypeSMS,
+	"voice": MFATypeCall,
+	"email": MFATypeEmail,
+}
+
+// idxProviders maps the keys of Identity Engine authenticators to the factor providers of the
+// classic authentication API, so that a preferred factor such as "GOOGLE:token:software:totp"
+// matches with both.
+var idxProviders = map[string]string{
+	"okta_verify":  "OKTA",
+	"okta_email":   "OKTA",
+	"phone_number": "OKTA",
+	"google_otp":   "GOOGLE",
+}
+
+// selectAuthenticator returns the ID and method type of the authenticator to use from the
+// options of a remediation. The password authenticator is selected automatically unless the
+// preferred factor is offered, otherwise authenticators are selected like factors of the classic
+// authentication API.
+func selectAuthenticator(resp *IDXResponse, rem *Remediation, preferred string, interactive bool) (string, string, error) {
+	if rem.field("authenticator") == nil {
+		return "", "", fmt.Errorf("no authenticator to select in %s", rem.Name)
+	}
+
+	factors, methods, passwordID := idxFactors(resp, rem)
+	if passwordID != "" && !offersFactor(factors, preferred) {
+		return passwordID, "password", nil
+	}
+
+	f, err := getFactor(factors, preferred, interactive)
+	if err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSuffix(f.ID, ":"+methods[f.ID]), methods[f.ID], nil
+}
+
+// idxFactors returns the authenticator methods offered by a remediation as factors of the classic
+// authentication API. The ID of a factor is made of the authenticator ID and the method type, which
+// are mapped to each other in methods. The password authenticator isn't returned as a factor, but
+// its ID is returned as passwordID.
+func idxFactors(resp *IDXResponse, rem *Remediation) (factors []Factor, methods map[string]string, passwordID string) {
+	methods = map[string]string{}
+
+	field := rem.field("authenticator")
+	if field == nil {
+		return nil, methods, ""
+	}
+
+	keys := map[string]string{}
+	for _, a := range resp.Authenticators.Value {
+		keys[a.ID] = a.Key
+	}
+
+	for _, o := range field.Options {
+		var v struct {
+			Form struct {
+				Value []FormValue `json:"value"`
+			} `json:"form"`
+		}
+		if err := json.Unmarshal(o.Value, &v); err != nil {
+			continue
+		}
+		var id string
+		var types []string
+		for _, f := range v.Form.Value {
+			switch f.Name {
+			case "id":
+				_ = json.Unmarshal(f.Value, &id)
+			case "methodType":
+				var mt string
+				if json.Unmarshal(f.Value, &mt) == nil && mt != "" {
+					types = append(types, mt)
+				}
+				for _, mo := range f.Options {
+					if json.Unmarshal(mo.Value, &mt) == nil {
+						types = append(types, mt)
+					}
+				}
+			}
+		}
+
+		provider, ok := idxProviders[keys[id]]
+		if !ok {
+			provider = strings.ToUpper(o.Label)
+		}
+		for _, mt := range types {
+			if mt == "password" {
+				passwordID = id
+				continue
+			}
+			factorType, ok := idxMethodTypes[mt]
+			if !ok {
+				factorType = mt
+			}
+			// The ID identifies the authenticator and its method
+			key := id + ":" + mt
+			methods[key] = mt
+			factors = append(factors, Factor{ID: key, FactorType: factorType, Provider: provider})
+		}
+	}
+
+	return factors, methods, passwordID
+}
+
+// offersFactor returns true if one of the factors matches the preferred factor.
+func offersFactor(factors []Factor, preferred string) bool {
+	if preferred == "" {
+		return false
+	}
+	for _, f := range factors {
+		if matchesFactor(f, preferred) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenSessionURL loads a URL which sets the session cookie, such as the success redirect of an
+// Identity Engine interaction.
+func (c *Client) OpenSessionURL(url string) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending HTTP request: %v", err)
+	}
+	resp.Body.Close()
+
+	if c.SessionID() == "" {
+		return errors.New("no session cookie returned")
+	}
+
+	return nil
+}
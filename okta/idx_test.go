@@ -0,0 +1,440 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"context"
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// getIDXTestServer returns a stand-in for an Okta Identity Engine org which requires a password
+// and an Okta Verify push, approved on the second poll.
+func getIDXTestServer(t *testing.T) *httptest.Server {
+	var ts *httptest.Server
+	var polls int
+
+	remediate := func(w http.ResponseWriter, r *http.Request, resp string) {
+		var body map[string]interface{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "fake_state_handle", body["stateHandle"])
+		w.Header().Set("Content-Type", idxContentType)
+		fmt.Fprint(w, resp)
+	}
+	selectAuthenticator := func(options string) string {
+		return fmt.Sprintf(`{"stateHandle":"fake_state_handle","remediation":{"value":[
+			{"name":"select-authenticator-authenticate","href":"%s/idp/idx/challenge","value":[
+				{"name":"authenticator","options":[%s]}]}]}}`, ts.URL, options)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/okta-organization", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"fake_org","pipeline":"idx"}`)
+	})
+	mux.HandleFunc("/home/amazon_aws/app", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("sid"); err == nil && cookie.Value == "fake_sid" {
+			fmt.Fprint(w, `<html><form id="appForm"><input name="SAMLResponse" value="fake_assertion"></form></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><script>var stateToken = 'fake\x2Dstate\x2Dtoken';</script></html>`)
+	})
+	mux.HandleFunc("/idp/idx/introspect", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "fake-state-token", body["stateToken"])
+		fmt.Fprintf(w, `{"stateHandle":"fake_state_handle","remediation":{"value":[
+			{"name":"identify","href":"%s/idp/idx/identify","value":[{"name":"identifier"}]}]}}`, ts.URL)
+	})
+	mux.HandleFunc("/idp/idx/identify", func(w http.ResponseWriter, r *http.Request) {
+		remediate(w, r, selectAuthenticator(`{"label":"Password","value":{"form":{"value":[
+			{"name":"id","value":"fake_password_id"},{"name":"methodType","value":"password"}]}}}`))
+	})
+	mux.HandleFunc("/idp/idx/challenge", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Authenticator struct {
+				ID         string `json:"id"`
+				MethodType string `json:"methodType"`
+			} `json:"authenticator"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		switch body.Authenticator.ID {
+		case "fake_password_id":
+			fmt.Fprintf(w, `{"stateHandle":"fake_state_handle",
+				"currentAuthenticatorEnrollment":{"value":{"type":"password","displayName":"Password"}},
+				"remediation":{"value":[{"name":"challenge-authenticator","href":"%s/idp/idx/challenge/answer"}]}}`, ts.URL)
+		case "fake_okta_verify_id":
+			assert.Equal(t, "push", body.Authenticator.MethodType)
+			fmt.Fprintf(w, `{"stateHandle":"fake_state_handle",
+				"currentAuthenticator":{"value":{"contextualData":{"correctAnswer":"42"}}},
+				"remediation":{"value":[{"name":"challenge-poll","href":"%s/idp/idx/authenticators/poll"}]}}`, ts.URL)
+		default:
+			t.Errorf("unexpected authenticator %s", body.Authenticator.ID)
+		}
+	})
+	mux.HandleFunc("/idp/idx/challenge/answer", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Credentials struct {
+				Passcode string `json:"passcode"`
+			} `json:"credentials"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Credentials.Passcode != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"messages":{"value":[{"message":"Password is incorrect","class":"ERROR"}]}}`)
+			return
+		}
+		fmt.Fprint(w, selectAuthenticator(`{"label":"Okta Verify","value":{"form":{"value":[
+			{"name":"id","value":"fake_okta_verify_id"},
+			{"name":"methodType","options":[{"label":"Push","value":"push"},{"label":"Code","value":"totp"}]}]}}}`))
+	})
+	mux.HandleFunc("/idp/idx/authenticators/poll", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			remediate(w, r, fmt.Sprintf(`{"stateHandle":"fake_state_handle","remediation":{"value":[
+				{"name":"challenge-poll","href":"%s/idp/idx/authenticators/poll"}]}}`, ts.URL))
+			return
+		}
+		remediate(w, r, fmt.Sprintf(`{"success":{"name":"success-redirect","href":"%s/login/token/redirect"}}`, ts.URL))
+	})
+	mux.HandleFunc("/login/token/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "fake_sid", Path: "/"})
+	})
+
+	ts = httptest.NewServer(mux)
+	return ts
+}
+
+func TestIDXLogin(t *testing.T) {
+	ts := getIDXTestServer(t)
+	defer ts.Close()
+
+	cases := []struct {
+		Name          string
+		Password      string
+		ExpectedError string
+	}{
+		{Name: "Success", Password: "secret"},
+		{
+			Name:          "WrongPassword",
+			Password:      "wrong",
+			ExpectedError: "logging in using the Identity Engine: challenge-authenticator: Password is incorrect",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			keyChain = testKeychain{"okta": []byte(tc.Password)}
+
+			c, err := NewClient(ts.URL)
+			assert.Nil(t, err)
+			p := &config.OktaProviderConfig{BaseURL: ts.URL, Username: "user", MFAFactor: MFATypePush, MFAInterval: 1}
+			appURL := ts.URL + "/home/amazon_aws/app"
+
+			st, err := login(c, "okta", p, appURL, false)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "", st)
+			assert.Equal(t, "fake_sid", c.SessionID())
+
+			saml, err := c.LaunchApp(&LaunchAppParams{URL: appURL})
+			assert.Nil(t, err)
+			assert.Equal(t, "fake_assertion", *saml)
+		})
+	}
+}
+
+// getIDXEmailTestServer returns a stand-in for an Okta Identity Engine org which offers a password
+// and an email authenticator. The email challenge accepts the code 123456, or succeeds once it has
+// been polled the given number of times, as if the link had been clicked.
+func getIDXEmailTestServer(t *testing.T, linkPolls int) *httptest.Server {
+	var ts *httptest.Server
+	var polls int
+
+	success := func() string {
+		return fmt.Sprintf(`{"success":{"name":"success-redirect","href":"%s/login/token/redirect"}}`, ts.URL)
+	}
+	challenge := func() string {
+		return fmt.Sprintf(`{"stateHandle":"fake_state_handle",
+			"currentAuthenticatorEnrollment":{"value":{"type":"email","displayName":"Email","profile":{"email":"u***@example.com"}}},
+			"remediation":{"value":[
+				{"name":"challenge-authenticator","href":"%[1]s/idp/idx/challenge/answer"},
+				{"name":"challenge-poll","href":"%[1]s/idp/idx/challenge/poll"}]}}`, ts.URL)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/home/amazon_aws/app", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><script>var stateToken = 'fake_state_token';</script></html>`)
+	})
+	mux.HandleFunc("/idp/idx/introspect", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"stateHandle":"fake_state_handle","remediation":{"value":[
+			{"name":"identify","href":"%s/idp/idx/identify","value":[{"name":"identifier"}]}]}}`, ts.URL)
+	})
+	mux.HandleFunc("/idp/idx/identify", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"stateHandle":"fake_state_handle","remediation":{"value":[
+			{"name":"select-authenticator-authenticate","href":"%s/idp/idx/challenge","value":[
+				{"name":"authenticator","options":[
+					{"label":"Password","value":{"form":{"value":[
+						{"name":"id","value":"fake_password_id"},{"name":"methodType","value":"password"}]}}},
+					{"label":"Email","value":{"form":{"value":[
+						{"name":"id","value":"fake_email_id"},{"name":"methodType","value":"email"}]}}}]}]}]}}`, ts.URL)
+	})
+	mux.HandleFunc("/idp/idx/challenge", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Authenticator struct {
+				ID string `json:"id"`
+			} `json:"authenticator"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "fake_email_id", body.Authenticator.ID)
+		fmt.Fprint(w, challenge())
+	})
+	mux.HandleFunc("/idp/idx/challenge/answer", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Credentials struct {
+				Passcode string `json:"passcode"`
+			} `json:"credentials"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Credentials.Passcode != "123456" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"messages":{"value":[{"message":"Invalid code. Try again.","class":"ERROR"}]}}`)
+			return
+		}
+		fmt.Fprint(w, success())
+	})
+	mux.HandleFunc("/idp/idx/challenge/poll", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if linkPolls == 0 || polls < linkPolls {
+			fmt.Fprint(w, challenge())
+			return
+		}
+		fmt.Fprint(w, success())
+	})
+	mux.HandleFunc("/login/token/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "fake_sid", Path: "/"})
+	})
+
+	ts = httptest.NewServer(mux)
+	return ts
+}
+
+func TestIDXEmail(t *testing.T) {
+	cases := []struct {
+		Name          string
+		LinkPolls     int
+		Input         string
+		ExpectedError string
+	}{
+		{Name: "Code", Input: "123456\n"},
+		{Name: "MagicLink", LinkPolls: 2},
+		{Name: "WrongCode", Input: "654321\n", ExpectedError: "challenge-authenticator: Invalid code. Try again."},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ts := getIDXEmailTestServer(t, tc.LinkPolls)
+			defer ts.Close()
+
+			c, err := NewClient(ts.URL)
+			assert.Nil(t, err)
+
+			// The preferred email factor is selected ahead of the password
+			p := &idxParams{
+				StartURL:  ts.URL + "/home/amazon_aws/app",
+				Username:  "user",
+				MFAFactor: MFATypeEmail,
+				Interval:  time.Millisecond,
+			}
+			testutil.WithStdin(t, tc.Input, func() {
+				err = idxLogin(c, p)
+			})
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "fake_sid", c.SessionID())
+		})
+	}
+}
+
+func TestUseIDX(t *testing.T) {
+	ts := getIDXTestServer(t)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	assert.True(t, useIDX(c, &config.OktaProviderConfig{}))
+	assert.False(t, useIDX(c, &config.OktaProviderConfig{AuthFlow: AuthFlowClassic}))
+
+	// Orgs which can't be detected use the classic flow
+	c, err = NewClient("http://127.0.0.1:1")
+	assert.Nil(t, err)
+	assert.False(t, useIDX(c, &config.OktaProviderConfig{}))
+	assert.True(t, useIDX(c, &config.OktaProviderConfig{AuthFlow: AuthFlowIDX}))
+}
+
+func TestMFATimings(t *testing.T) {
+	pushTimeout, interval := mfaTimings(&config.OktaProviderConfig{})
+	assert.Equal(t, MFAPushTimeout*time.Second, pushTimeout)
+	assert.Equal(t, MFAInterval*time.Second, interval)
+}
+
+// getIDXPushTestServer returns a stand-in for an Okta Identity Engine org waiting for an Okta
+// Verify push which is never approved. Okta Verify and Google Authenticator codes are offered
+// instead, accepting the code 123456.
+func getIDXPushTestServer(t *testing.T, cancelled *bool) (*httptest.Server, func() *IDXResponse) {
+	var ts *httptest.Server
+
+	pending := func() string {
+		return fmt.Sprintf(`{"stateHandle":"fake_state_handle",
+			"remediation":{"value":[
+				{"name":"challenge-poll","href":"%[1]s/idp/idx/authenticators/poll"},
+				{"name":"select-authenticator-authenticate","href":"%[1]s/idp/idx/challenge","value":[
+					{"name":"authenticator","options":[
+						{"label":"Okta Verify","value":{"form":{"value":[
+							{"name":"id","value":"fake_okta_verify_id"},
+							{"name":"methodType","options":[{"label":"Push","value":"push"},{"label":"Code","value":"totp"}]}]}}},
+						{"label":"Google Authenticator","value":{"form":{"value":[
+							{"name":"id","value":"fake_google_id"},{"name":"methodType","value":"otp"}]}}}]}]}]},
+			"authenticators":{"value":[
+				{"id":"fake_okta_verify_id","key":"okta_verify","displayName":"Okta Verify"},
+				{"id":"fake_google_id","key":"google_otp","displayName":"Google Authenticator"}]},
+			"cancel":{"name":"cancel","href":"%[1]s/idp/idx/cancel"}}`, ts.URL)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/idp/idx/authenticators/poll", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, pending())
+	})
+	mux.HandleFunc("/idp/idx/challenge", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Authenticator struct {
+				ID         string `json:"id"`
+				MethodType string `json:"methodType"`
+			} `json:"authenticator"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "fake_okta_verify_id", body.Authenticator.ID)
+		assert.Equal(t, "totp", body.Authenticator.MethodType)
+		fmt.Fprintf(w, `{"stateHandle":"fake_state_handle",
+			"currentAuthenticatorEnrollment":{"value":{"type":"app","displayName":"Okta Verify"}},
+			"remediation":{"value":[{"name":"challenge-authenticator","href":"%s/idp/idx/challenge/answer"}]}}`, ts.URL)
+	})
+	mux.HandleFunc("/idp/idx/challenge/answer", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Credentials struct {
+				Passcode string `json:"passcode"`
+			} `json:"credentials"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "123456", body.Credentials.Passcode)
+		fmt.Fprintf(w, `{"success":{"name":"success-redirect","href":"%s/login/token/redirect"}}`, ts.URL)
+	})
+	mux.HandleFunc("/idp/idx/cancel", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "fake_state_handle", body["stateHandle"])
+		*cancelled = true
+		fmt.Fprint(w, `{"stateHandle":"fake_state_handle"}`)
+	})
+	mux.HandleFunc("/login/token/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "fake_sid", Path: "/"})
+	})
+
+	ts = httptest.NewServer(mux)
+
+	return ts, func() *IDXResponse {
+		var resp IDXResponse
+		assert.Nil(t, json.Unmarshal([]byte(pending()), &resp))
+		return &resp
+	}
+}
+
+func TestIDXPush(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Interrupted   bool
+		Input         string
+		ExpectedError error
+	}{
+		{Name: "TimeoutFallbackToTOTP", Input: "123456\n"},
+		{Name: "Interrupted", Interrupted: true, ExpectedError: idp.ErrCanceled},
+	}
+
+	defer func(n func() (context.Context, context.CancelFunc)) { notifyInterrupt = n }(notifyInterrupt)
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cancelled bool
+			ts, pending := getIDXPushTestServer(t, &cancelled)
+			defer ts.Close()
+
+			notifyInterrupt = func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				if tc.Interrupted {
+					cancel()
+				}
+				return ctx, cancel
+			}
+
+			c, err := NewClient(ts.URL)
+			assert.Nil(t, err)
+
+			p := &idxParams{PushTimeout: 5 * time.Millisecond, Interval: time.Millisecond}
+			testutil.WithStdin(t, tc.Input, func() {
+				err = idxRemediate(c, pending(), p)
+			})
+			assert.Equal(t, tc.ExpectedError, err)
+			// The interaction is cancelled if the user interrupts clisso.
+			assert.Equal(t, tc.Interrupted, cancelled)
+			if tc.ExpectedError == nil {
+				assert.Equal(t, "fake_sid", c.SessionID())
+			}
+		})
+	}
+}
+
+func TestSelectAuthenticator(t *testing.T) {
+	ts, pending := getIDXPushTestServer(t, new(bool))
+	defer ts.Close()
+
+	resp := pending()
+	rem := resp.remediation(remediationSelectAuthenticator)
+
+	// The preferred factor is matched like with the classic authentication API.
+	cases := []struct {
+		Preferred          string
+		ExpectedID         string
+		ExpectedMethodType string
+	}{
+		{Preferred: MFATypePush, ExpectedID: "fake_okta_verify_id", ExpectedMethodType: "push"},
+		{Preferred: MFATypeTOTP, ExpectedID: "fake_okta_verify_id", ExpectedMethodType: "totp"},
+		{Preferred: "OKTA:token:software:totp", ExpectedID: "fake_okta_verify_id", ExpectedMethodType: "totp"},
+		{Preferred: "google:token:software:totp", ExpectedID: "fake_google_id", ExpectedMethodType: "otp"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Preferred, func(t *testing.T) {
+			id, mt, err := selectAuthenticator(resp, rem, tc.Preferred, false)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.ExpectedID, id)
+			assert.Equal(t, tc.ExpectedMethodType, mt)
+		})
+	}
+}
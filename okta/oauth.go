@@ -0,0 +1,94 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/oauth"
+)
+
+const (
+	// deviceScope is the scope requested by the device auth flow. okta.apps.sso allows exchanging
+	// the tokens for a web SSO token, okta.users.read.self allows listing the user's apps.
+	deviceScope = "openid okta.apps.sso okta.users.read.self"
+
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	tokenTypeIDToken       = "urn:ietf:params:oauth:token-type:id_token"
+	tokenTypeWebSSOToken   = "urn:okta:oauth:token-type:web_sso_token"
+)
+
+// authorizeDevice runs the OAuth 2.0 device authorization flow with the Okta org authorization
+// server, which lets the user log in using any browser. The returned client is used to exchange
+// the tokens.
+func authorizeDevice(p *config.OktaProviderConfig, interactive bool) (*oauth.Client, *oauth.TokenResponse, error) {
+	oc := oauth.NewClient(p.BaseURL, p.ClientID, "")
+	if err := oc.Discover(); err != nil {
+		return nil, nil, fmt.Errorf("discovering authorization server: %v", err)
+	}
+
+	t, err := oauth.DeviceFlow(oc, deviceScope, interactive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return oc, t, nil
+}
+
+// deviceLogin logs in using the device auth flow and opens a session for the app at appURL. The
+// tokens are exchanged for a web SSO token, which Okta accepts in place of a password to open a
+// session.
+func deviceLogin(c *Client, p *config.OktaProviderConfig, appURL string, interactive bool) error {
+	appID, err := appInstanceID(appURL)
+	if err != nil {
+		return err
+	}
+
+	oc, t, err := authorizeDevice(p, interactive)
+	if err != nil {
+		return fmt.Errorf("authorizing device: %v", err)
+	}
+
+	sso, err := oc.Token(url.Values{
+		"grant_type":           {grantTypeTokenExchange},
+		"actor_token":          {t.AccessToken},
+		"actor_token_type":     {tokenTypeAccessToken},
+		"subject_token":        {t.IDToken},
+		"subject_token_type":   {tokenTypeIDToken},
+		"requested_token_type": {tokenTypeWebSSOToken},
+		"audience":             {"urn:okta:apps:" + appID},
+	})
+	if err != nil {
+		return fmt.Errorf("exchanging tokens for a web SSO token: %v", err)
+	}
+
+	err = c.OpenSessionURL(fmt.Sprintf("%s/login/token/sso?token=%s", c.BaseURL, url.QueryEscape(sso.AccessToken)))
+	if err != nil {
+		return fmt.Errorf("opening session: %v", err)
+	}
+
+	return nil
+}
+
+// appInstanceID returns the ID of the app instance an app embed link launches, e.g.
+// 0oa1b2c3d4 for https://example.okta.com/home/amazon_aws/0oa1b2c3d4/272.
+func appInstanceID(appURL string) (string, error) {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing app URL: %v", err)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "home" || parts[2] == "" {
+		return "", fmt.Errorf("no app ID in app URL %s", appURL)
+	}
+
+	return parts[2], nil
+}
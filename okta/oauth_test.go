@@ -0,0 +1,117 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingKeychain fails the test when a password is read.
+type failingKeychain struct{ t *testing.T }
+
+func (k failingKeychain) Get(provider string) ([]byte, error) {
+	k.t.Error("password read from key chain")
+	return nil, errors.New("no password")
+}
+func (k failingKeychain) Set(provider string, password []byte) error { return nil }
+
+// getDeviceTestServer returns a stand-in for an Okta org which authorizes the device on the first
+// poll and exchanges the tokens for a web SSO token for app 0oaapp.
+func getDeviceTestServer(t *testing.T) *httptest.Server {
+	var ts *httptest.Server
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":"%[1]s","token_endpoint":"%[1]s/oauth2/v1/token",`+
+			`"device_authorization_endpoint":"%[1]s/oauth2/v1/device/authorize"}`, ts.URL)
+	})
+	mux.HandleFunc("/oauth2/v1/device/authorize", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fake_client_id", r.FormValue("client_id"))
+		assert.Equal(t, deviceScope, r.FormValue("scope"))
+		fmt.Fprintf(w, `{"device_code":"fake_device_code","user_code":"ABCD-EFGH",`+
+			`"verification_uri":"%s/activate","expires_in":60,"interval":1}`, ts.URL)
+	})
+	mux.HandleFunc("/oauth2/v1/token", func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			assert.Equal(t, "fake_device_code", r.FormValue("device_code"))
+			fmt.Fprint(w, `{"access_token":"fake_access_token","id_token":"fake_id_token","token_type":"Bearer"}`)
+		case grantTypeTokenExchange:
+			assert.Equal(t, "fake_access_token", r.FormValue("actor_token"))
+			assert.Equal(t, "fake_id_token", r.FormValue("subject_token"))
+			assert.Equal(t, tokenTypeWebSSOToken, r.FormValue("requested_token_type"))
+			assert.Equal(t, "urn:okta:apps:0oaapp", r.FormValue("audience"))
+			fmt.Fprint(w, `{"access_token":"fake_sso_token","token_type":"N_A"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"unsupported_grant_type"}`)
+		}
+	})
+	mux.HandleFunc("/login/token/sso", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fake_sso_token", r.URL.Query().Get("token"))
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "fake_sid", Path: "/"})
+	})
+	mux.HandleFunc("/api/v1/users/me/appLinks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake_access_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `[{"label":"AWS","linkUrl":"https://example.okta.com/home/amazon_aws/0oaapp/272","appName":"amazon_aws"},`+
+			`{"label":"Other","appName":"other"}]`)
+	})
+
+	ts = httptest.NewServer(mux)
+	return ts
+}
+
+func TestDeviceLogin(t *testing.T) {
+	ts := getDeviceTestServer(t)
+	defer ts.Close()
+
+	keyChain = failingKeychain{t}
+	p := &config.OktaProviderConfig{BaseURL: ts.URL, AuthFlow: AuthFlowDevice, ClientID: "fake_client_id"}
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	st, err := login(c, "okta", p, ts.URL+"/home/amazon_aws/0oaapp/272", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "", st)
+	assert.Equal(t, "fake_sid", c.SessionID())
+}
+
+func TestDeviceAppLinks(t *testing.T) {
+	ts := getDeviceTestServer(t)
+	defer ts.Close()
+
+	keyChain = failingKeychain{t}
+	p := &config.OktaProviderConfig{BaseURL: ts.URL, AuthFlow: AuthFlowDevice, ClientID: "fake_client_id"}
+
+	testutil.TempTokenCache(t)
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	links, err := awsAppLinks(c, "okta", p, false)
+	assert.Nil(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, "AWS", links[0].Label)
+}
+
+func TestAppInstanceID(t *testing.T) {
+	id, err := appInstanceID("https://example.okta.com/home/amazon_aws/0oa1b2c3d4/272")
+	assert.Nil(t, err)
+	assert.Equal(t, "0oa1b2c3d4", id)
+
+	_, err = appInstanceID("https://example.okta.com/")
+	assert.EqualError(t, err, "no app ID in app URL https://example.okta.com/")
+}
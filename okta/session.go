@@ -0,0 +1,124 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/tokencache"
+)
+
+// cachedSession is an Okta session cached across invocations of clisso.
+type cachedSession struct {
+	SessionID string    `json:"sessionId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// sessionCacheKey returns the cache key of the session for the given provider.
+func sessionCacheKey(provider, baseURL string) string {
+	return fmt.Sprintf("okta-session:%s:%s", provider, baseURL)
+}
+
+// restoreSession sets the session cookie of the client to the cached session, if there is one
+// which hasn't expired yet. It returns false otherwise.
+func restoreSession(c *Client, key string) bool {
+	var cs cachedSession
+	ok, err := tokencache.Load(key, &cs)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't read cached Okta session")
+		return false
+	}
+	if !ok || cs.SessionID == "" {
+		return false
+	}
+	if !cs.ExpiresAt.IsZero() && time.Now().After(cs.ExpiresAt) {
+		log.Debug("Cached Okta session expired")
+		deleteSession(key)
+		return false
+	}
+
+	if err = c.SetSessionID(cs.SessionID); err != nil {
+		log.WithError(err).Warn("Couldn't use cached Okta session")
+		return false
+	}
+
+	return true
+}
+
+// launchWithCachedSession launches the app using the cached session, if there is one. It returns
+// false if there is no usable session, in which case the user needs to log in. A session rejected
+// by Okta is removed from the cache. launch launches the app using the session cookie when passed
+// an empty session token.
+func launchWithCachedSession(c *Client, key string, launch func(sessionToken string) (string, error)) (string, bool) {
+	if !restoreSession(c, key) {
+		return "", false
+	}
+	sid := c.SessionID()
+
+	saml, err := launch("")
+	if err != nil {
+		// Okta redirects to the login page if the session is no longer valid.
+		log.WithError(err).Debug("Cached Okta session rejected, logging in again")
+		deleteSession(key)
+		return "", false
+	}
+	log.Debug("Launched app using cached Okta session")
+
+	// Step-up authentication may have replaced the session
+	if c.SessionID() != sid {
+		saveSession(c, key)
+	}
+
+	return saml, true
+}
+
+// saveSession caches the session the client obtained when launching an app, so it can be reused
+// by later invocations until it expires.
+func saveSession(c *Client, key string) {
+	sid := c.SessionID()
+	if sid == "" {
+		log.Debug("Okta didn't set a session cookie, not caching the session")
+		return
+	}
+
+	cs := cachedSession{SessionID: sid}
+	if s, err := c.GetSession(); err == nil {
+		cs.ExpiresAt = s.ExpiresAt
+	} else {
+		log.WithError(err).Debug("Couldn't get Okta session expiration")
+	}
+
+	if err := tokencache.Save(key, &cs); err != nil {
+		log.WithError(err).Warn("Couldn't cache Okta session")
+	}
+}
+
+// deleteSession removes the cached session.
+func deleteSession(key string) {
+	if err := tokencache.Delete(key); err != nil {
+		log.WithError(err).Warn("Couldn't delete cached Okta session")
+	}
+}
+
+// DeleteCache removes the session and the device token cached for the given provider.
+func DeleteCache(provider string) error {
+	p, err := config.GetOktaProvider(provider)
+	if err != nil {
+		return fmt.Errorf("reading provider config: %v", err)
+	}
+
+	if err := tokencache.Delete(sessionCacheKey(provider, p.BaseURL)); err != nil {
+		return fmt.Errorf("deleting cached session: %v", err)
+	}
+	if err := tokencache.Delete(deviceTokenCacheKey(provider)); err != nil {
+		return fmt.Errorf("deleting device token: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/tokencache"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// getSessionTestServer returns a stand-in for Okta which sets a session cookie when an app is
+// launched with a session token and accepts the cookie until the session is revoked.
+func getSessionTestServer(t *testing.T, validSID *string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/home/amazon_aws/app", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sessionToken") == "fake_token" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: *validSID, Path: "/"})
+		} else if cookie, err := r.Cookie("sid"); err != nil || cookie.Value != *validSID {
+			fmt.Fprint(w, `<html><form id="login"></form></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><form id="appForm"><input name="SAMLResponse" value="fake_assertion"></form></html>`)
+	})
+	mux.HandleFunc("/api/v1/sessions/me", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("sid")
+		if err != nil || cookie.Value != *validSID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"%s","status":"ACTIVE","expiresAt":"%s"}`, *validSID,
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestSessionReuse(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	sid := "sid1"
+	ts := getSessionTestServer(t, &sid)
+	defer ts.Close()
+
+	key := sessionCacheKey("okta", ts.URL)
+	appURL := ts.URL + "/home/amazon_aws/app"
+	var c *Client
+	launch := func(st string) (string, error) {
+		saml, err := c.LaunchApp(&LaunchAppParams{SessionToken: st, URL: appURL})
+		return derefString(saml), err
+	}
+
+	// No session cached yet.
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+	_, ok := launchWithCachedSession(c, key, launch)
+	assert.False(t, ok)
+
+	// Log in and cache the session.
+	saml, err := c.LaunchApp(&LaunchAppParams{SessionToken: "fake_token", URL: appURL})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", *saml)
+	assert.Equal(t, "sid1", c.SessionID())
+	saveSession(c, key)
+
+	var cs cachedSession
+	ok, err = tokencache.Load(key, &cs)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "sid1", cs.SessionID)
+	assert.True(t, cs.ExpiresAt.After(time.Now()))
+
+	// A new client reuses the cached session.
+	c, err = NewClient(ts.URL)
+	assert.Nil(t, err)
+	assertion, ok := launchWithCachedSession(c, key, launch)
+	assert.True(t, ok)
+	assert.Equal(t, "fake_assertion", assertion)
+
+	// A session rejected by Okta is removed from the cache.
+	sid = "sid2"
+	c, err = NewClient(ts.URL)
+	assert.Nil(t, err)
+	_, ok = launchWithCachedSession(c, key, launch)
+	assert.False(t, ok)
+	ok, err = tokencache.Load(key, &cs)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestExpiredSession(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	key := sessionCacheKey("okta", "https://example.okta.com")
+	assert.Nil(t, tokencache.Save(key, &cachedSession{SessionID: "sid", ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	c, err := NewClient("https://example.okta.com")
+	assert.Nil(t, err)
+	_, ok := launchWithCachedSession(c, key, func(string) (string, error) {
+		t.Error("app launched using expired session")
+		return "", nil
+	})
+	assert.False(t, ok)
+
+	ok, err = tokencache.Load(key, &cachedSession{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestDeleteCache(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	viper.Set("providers.test-okta-delete", map[string]interface{}{
+		"type":     "okta",
+		"base-url": "https://example.okta.com",
+	})
+
+	key := sessionCacheKey("test-okta-delete", "https://example.okta.com")
+	assert.Nil(t, tokencache.Save(key, &cachedSession{SessionID: "sid"}))
+	_, err := deviceToken("test-okta-delete")
+	assert.Nil(t, err)
+
+	assert.Nil(t, DeleteCache("test-okta-delete"))
+
+	ok, err := tokencache.Load(key, &cachedSession{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = tokencache.Load(deviceTokenCacheKey("test-okta-delete"), &cachedDeviceToken{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,189 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package okta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/stretchr/testify/assert"
+)
+
+// testKeychain is an in-memory key chain.
+type testKeychain map[string][]byte
+
+func (k testKeychain) Get(provider string) ([]byte, error) { return k[provider], nil }
+func (k testKeychain) Set(provider string, password []byte) error {
+	k[provider] = password
+	return nil
+}
+
+// getPasswordTestServer returns a stand-in for the Okta API which accepts the password change from
+// old to new2, rejecting new1 because of the password policy.
+func getPasswordTestServer(t *testing.T, skipped *bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/authn/credentials/change_password", func(w http.ResponseWriter, r *http.Request) {
+		var p ChangePasswordParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p.StateToken)
+		assert.Equal(t, "old", p.OldPassword)
+		if p.NewPassword != "new2" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errorCode":"E0000080","errorSummary":"Password requirements were not met"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"fake_token"}`)
+	})
+	mux.HandleFunc("/api/v1/authn/skip", func(w http.ResponseWriter, r *http.Request) {
+		*skipped = true
+		fmt.Fprint(w, `{"status":"SUCCESS","sessionToken":"fake_token"}`)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestCompleteTransaction(t *testing.T) {
+	defer func(k keychain.Keychain, r func() ([]byte, error)) { keyChain, readPassword = k, r }(keyChain, readPassword)
+
+	cases := []struct {
+		Name             string
+		Status           string
+		Interactive      bool
+		Input            string
+		Passwords        []string
+		ExpectedToken    string
+		ExpectedError    string
+		ExpectedSkipped  bool
+		ExpectedPassword string
+	}{
+		{
+			Name:          "Success",
+			Status:        StatusSuccess,
+			ExpectedToken: "fake_token",
+		},
+		{
+			Name:          "LockedOut",
+			Status:        StatusLockedOut,
+			ExpectedError: "your Okta account is locked out, please unlock it using the Okta web UI or contact your Okta administrator",
+		},
+		{
+			Name:          "MFAEnroll",
+			Status:        StatusMFAEnroll,
+			ExpectedError: "no MFA factor enrolled, please enroll one using the Okta web UI",
+		},
+		{
+			Name:          "PasswordExpiredNonInteractive",
+			Status:        StatusPasswordExpired,
+			ExpectedError: "your Okta password has expired, please change it using the Okta web UI",
+		},
+		{
+			Name:        "PasswordExpired",
+			Status:      StatusPasswordExpired,
+			Interactive: true,
+			// A mismatching confirmation and a password violating the policy are retried.
+			Passwords:        []string{"new1", "typo", "new1", "new1", "new2", "new2"},
+			ExpectedToken:    "fake_token",
+			ExpectedPassword: "new2",
+		},
+		{
+			Name:             "PasswordWarnSkipped",
+			Status:           StatusPasswordWarn,
+			Interactive:      true,
+			Input:            "n\n",
+			ExpectedToken:    "fake_token",
+			ExpectedSkipped:  true,
+			ExpectedPassword: "old",
+		},
+		{
+			Name:             "PasswordWarnNonInteractive",
+			Status:           StatusPasswordWarn,
+			ExpectedToken:    "fake_token",
+			ExpectedSkipped:  true,
+			ExpectedPassword: "old",
+		},
+		{
+			Name:             "PasswordWarnChanged",
+			Status:           StatusPasswordWarn,
+			Interactive:      true,
+			Input:            "y\n",
+			Passwords:        []string{"new2", "new2"},
+			ExpectedToken:    "fake_token",
+			ExpectedPassword: "new2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			kc := testKeychain{"okta": []byte("old")}
+			keyChain = kc
+			passwords := tc.Passwords
+			readPassword = func() ([]byte, error) {
+				p := passwords[0]
+				passwords = passwords[1:]
+				return []byte(p), nil
+			}
+
+			var skipped bool
+			ts := getPasswordTestServer(t, &skipped)
+			defer ts.Close()
+
+			c := Client{BaseURL: ts.URL}
+			resp := &GetSessionTokenResponse{Status: tc.Status, StateToken: "fake_state_token", SessionToken: "fake_token"}
+			testutil.WithStdin(t, tc.Input, func() {
+				token, err := completeTransaction(&c, resp, &transactionParams{
+					Provider:    "okta",
+					Password:    "old",
+					Config:      &config.OktaProviderConfig{},
+					Interactive: tc.Interactive,
+				})
+				if tc.ExpectedError != "" {
+					assert.EqualError(t, err, tc.ExpectedError)
+					return
+				}
+				assert.Nil(t, err)
+				assert.Equal(t, tc.ExpectedToken, token)
+			})
+			assert.Equal(t, tc.ExpectedSkipped, skipped)
+			if tc.ExpectedPassword != "" {
+				assert.Equal(t, tc.ExpectedPassword, string(kc["okta"]))
+			}
+			assert.Empty(t, passwords)
+		})
+	}
+}
+
+func TestCompleteStepUp(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/authn", func(w http.ResponseWriter, r *http.Request) {
+		var p GetSessionTokenParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "fake_state_token", p.StateToken)
+		if p.Password == "" {
+			fmt.Fprint(w, `{"stateToken":"fake_state_token","status":"UNAUTHENTICATED"}`)
+			return
+		}
+		assert.Equal(t, "user", p.Username)
+		assert.Equal(t, "secret", p.Password)
+		fmt.Fprint(w, `{"sessionToken":"fake_session_token","status":"SUCCESS"}`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	keyChain = testKeychain{"okta": []byte("secret")}
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	p := &config.OktaProviderConfig{BaseURL: ts.URL, Username: "user", AuthFlow: AuthFlowClassic}
+	st, err := completeStepUp(c, "okta", p, &StepUpError{StateToken: "fake_state_token"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_session_token", st)
+}
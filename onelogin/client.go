@@ -17,6 +17,10 @@ import (
 	"github.com/allcloud-io/clisso/log"
 )
 
+// errUnauthorized is returned for requests rejected with HTTP 401, e.g. because the access token
+// has been revoked.
+var errUnauthorized = errors.New("401 Unauthorized")
+
 // Client represents a OneLogin API client.
 type Client struct {
 	http.Client
@@ -24,7 +28,13 @@ type Client struct {
 }
 
 type GenerateTokensParams struct {
-	GrantType string `json:"grant_type"`
+	GrantType    string `json:"grant_type"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type RevokeTokenParams struct {
+	AccessToken string `json:"access_token"`
 }
 
 type GenerateTokensResponse struct {
@@ -128,6 +138,9 @@ func (c *Client) doRequest(r *http.Request) (string, error) {
 		return "", fmt.Errorf("sending HTTP request: %v", err)
 	}
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", errUnauthorized
+	}
 	if resp.StatusCode != 200 {
 		return "", errors.New(resp.Status)
 	}
@@ -144,31 +157,64 @@ func (c *Client) doRequest(r *http.Request) (string, error) {
 
 // GenerateTokens generates the tokens required for interacting with the OneLogin
 // API.
-func (c *Client) GenerateTokens(clientID, clientSecret string) (string, error) {
-	headers := map[string]string{
-		"Authorization": fmt.Sprintf("client_id:%v, client_secret:%v", clientID, clientSecret),
-		"Content-Type":  "application/json",
-	}
-	body := GenerateTokensParams{GrantType: "client_credentials"}
+func (c *Client) GenerateTokens(clientID, clientSecret string) (*GenerateTokensResponse, error) {
+	return c.postTokens(clientID, clientSecret, &GenerateTokensParams{GrantType: "client_credentials"})
+}
+
+// RefreshTokens generates new tokens using the refresh token returned with an access token.
+func (c *Client) RefreshTokens(clientID, clientSecret, accessToken, refreshToken string) (*GenerateTokensResponse, error) {
+	return c.postTokens(clientID, clientSecret, &GenerateTokensParams{
+		GrantType:    "refresh_token",
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+}
 
-	req, err := makeRequest(http.MethodPost, c.Endpoints.GenerateTokens(), headers, &body)
+// postTokens calls the token endpoint using the API credentials.
+func (c *Client) postTokens(clientID, clientSecret string, body *GenerateTokensParams) (*GenerateTokensResponse, error) {
+	req, err := makeRequest(http.MethodPost, c.Endpoints.GenerateTokens(), credentialHeaders(clientID, clientSecret), body)
 	if err != nil {
-		return "", fmt.Errorf("creating request: %v", err)
+		return nil, fmt.Errorf("creating request: %v", err)
 	}
 
 	data, err := c.doRequest(req)
 	if err != nil {
-		return "", fmt.Errorf("doing HTTP request: %v", err)
+		return nil, fmt.Errorf("doing HTTP request: %v", err)
 	}
 
 	var resp GenerateTokensResponse
 	if err := json.Unmarshal([]byte(data), &resp); err != nil {
-		return "", fmt.Errorf("parsing HTTP response: %v", err)
+		return nil, fmt.Errorf("parsing HTTP response: %v", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("no access token returned")
+	}
+
+	return &resp, nil
+}
+
+// RevokeToken revokes an access token, along with its refresh token.
+func (c *Client) RevokeToken(clientID, clientSecret, accessToken string) error {
+	body := RevokeTokenParams{AccessToken: accessToken}
+	req, err := makeRequest(http.MethodPost, c.Endpoints.RevokeToken(), credentialHeaders(clientID, clientSecret), &body)
+	if err != nil {
+		return fmt.Errorf("creating request: %v", err)
 	}
 
-	// TODO add handling for valid JSON with wrong response
+	if _, err = c.doRequest(req); err != nil {
+		return fmt.Errorf("doing HTTP request: %v", err)
+	}
 
-	return resp.AccessToken, nil
+	return nil
+}
+
+// credentialHeaders returns the headers authenticating requests to the token endpoints using the
+// API credentials.
+func credentialHeaders(clientID, clientSecret string) map[string]string {
+	return map[string]string{
+		"Authorization": fmt.Sprintf("client_id:%v, client_secret:%v", clientID, clientSecret),
+		"Content-Type":  "application/json",
+	}
 }
 
 // GenerateSamlAssertion gets a OneLogin access token and a GenerateSamlAssertionParams struct
@@ -193,7 +239,7 @@ func (c *Client) GenerateSamlAssertion(token string, p *GenerateSamlAssertionPar
 		//if oneLoginError, ok := err.(*OneLoginError); ok {
 		//	fmt.Println(oneLoginError.StatusCode)
 		//}
-		return nil, fmt.Errorf("doing HTTP request: %v", err)
+		return nil, fmt.Errorf("doing HTTP request: %w", err)
 	}
 
 	var resp GenerateSamlAssertionResponse
@@ -232,11 +278,12 @@ func (c *Client) VerifyFactor(token string, p *VerifyFactorParams) (*VerifyFacto
 	return &resp, nil
 }
 
-// NewClient creates a new Client and returns a pointer to it.
-func NewClient(region string) (c *Client, err error) {
+// NewClient creates a new Client and returns a pointer to it. The API of the given region is used
+// unless baseURL is set.
+func NewClient(region, baseURL string) (c *Client, err error) {
 	c = new(Client)
 
-	c.Endpoints = Endpoints{Region: region}
+	c.Endpoints = Endpoints{Region: region, BaseURL: baseURL}
 	err = c.Endpoints.setBase()
 
 	return
@@ -39,7 +39,7 @@ func TestNewClient(t *testing.T) {
 		{"Invalid region", "invalid", true},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := NewClient(test.region)
+			_, err := NewClient(test.region, "")
 			if test.expectError && err == nil {
 				t.Errorf("expected error")
 			}
@@ -69,8 +69,11 @@ func TestGenerateTokens(t *testing.T) {
 	if err != nil {
 		t.Errorf("GenerateTokens failed: %s", err)
 	}
-	if resp != "fake_token" {
-		t.Errorf("Wrong response, got: %v, want: %v", resp, "fake_token")
+	if resp.AccessToken != "fake_token" {
+		t.Errorf("Wrong response, got: %v, want: %v", resp.AccessToken, "fake_token")
+	}
+	if resp.RefreshToken != "fake" || resp.ExpiresIn != 36000 {
+		t.Errorf("Wrong response, got: %+v", resp)
 	}
 }
 
@@ -6,8 +6,11 @@
 package onelogin
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/url"
+	"strings"
 )
 
 const (
@@ -20,6 +23,9 @@ const (
 	// GenerateTokensPath - OneLogin API endpoint to generate an access token and refresh token
 	GenerateTokensPath string = "/auth/oauth2/v2/token"
 
+	// RevokeTokenPath - OneLogin API endpoint to revoke an access token and its refresh token
+	RevokeTokenPath string = "/auth/oauth2/revoke"
+
 	// GetUserByEmailPath - OneLogin API endpoint to get a paginated list of users via email address
 	GetUserByEmailPath string = "/api/2/users?email=%s"
 
@@ -30,11 +36,18 @@ const (
 // Endpoints represent the OneLogin API HTTP endpoints.
 type Endpoints struct {
 	Region string
+	// BaseURL is the base URL of the API, used instead of the URL of the region if set.
+	BaseURL string
 
 	base *url.URL
 }
 
 func (e *Endpoints) setBase() (err error) {
+	if e.BaseURL != "" {
+		e.base, err = ParseBaseURL(e.BaseURL)
+		return
+	}
+
 	var base string
 	switch e.Region {
 	case "US":
@@ -64,6 +77,11 @@ func (e Endpoints) GenerateTokens() string {
 	return e.doURL(GenerateTokensPath, make(url.Values))
 }
 
+// RevokeToken will return the relevant Revoke Token endpoint for a base URL
+func (e Endpoints) RevokeToken() string {
+	return e.doURL(RevokeTokenPath, make(url.Values))
+}
+
 // GetUserByEmail will, given an email address, return a valid url
 // to search the Users endpoint by email address
 func (e Endpoints) GetUserByEmail(email string) string {
@@ -80,8 +98,45 @@ func (e Endpoints) doURL(endpoint string, params url.Values) string {
 		return ""
 	}
 
-	e.base.Path = endpoint
-	e.base.RawQuery = params.Encode()
+	u := *e.base
+	u.Path = strings.TrimSuffix(e.base.Path, "/") + endpoint
+	u.RawQuery = params.Encode()
 
-	return e.base.String()
+	return u.String()
+}
+
+// ParseBaseURL parses and validates a custom API base URL. The URL must use HTTPS, unless the host
+// is a loopback address, e.g. of a local stand-in server.
+func ParseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing API base URL: %v", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("API base URL %q has no host", s)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("API base URL %q must not have a query or fragment", s)
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !isLoopback(u.Hostname()) {
+			return nil, errors.New("API base URL must use HTTPS unless the host is a loopback address")
+		}
+	default:
+		return nil, fmt.Errorf("API base URL %q must use HTTPS", s)
+	}
+
+	return u, nil
+}
+
+// isLoopback returns true if host is localhost or a loopback IP address.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
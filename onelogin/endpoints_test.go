@@ -45,6 +45,43 @@ func TestEndpoints_SetBase(t *testing.T) {
 	}
 }
 
+func TestEndpoints_BaseURL(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		baseURL          string
+		expectVerifyPath string
+		expectError      bool
+	}{
+		{"HTTPS", "https://api.example.com", "https://api.example.com/api/2/saml_assertion/verify_factor", false},
+		{"Path prefix", "https://proxy.example.com/onelogin/", "https://proxy.example.com/onelogin/api/2/saml_assertion/verify_factor", false},
+		{"HTTP loopback", "http://127.0.0.1:8080", "http://127.0.0.1:8080/api/2/saml_assertion/verify_factor", false},
+		{"HTTP localhost", "http://localhost:8080", "http://localhost:8080/api/2/saml_assertion/verify_factor", false},
+		{"HTTP IPv6 loopback", "http://[::1]:8080", "http://[::1]:8080/api/2/saml_assertion/verify_factor", false},
+		{"HTTP remote host", "http://api.example.com", "", true},
+		{"Other scheme", "ftp://api.example.com", "", true},
+		{"No host", "https://", "", true},
+		{"Query", "https://api.example.com?foo=bar", "", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// The base URL takes precedence over the region
+			e := Endpoints{Region: "US", BaseURL: test.baseURL}
+
+			err := e.setBase()
+			if test.expectError && err == nil {
+				t.Errorf("expected error")
+			}
+
+			if !test.expectError && err != nil {
+				t.Errorf("unexpected error %+v", err)
+			}
+
+			if test.expectVerifyPath != e.VerifyFactor() {
+				t.Errorf("expected %q, received %q", test.expectVerifyPath, e.VerifyFactor())
+			}
+		})
+	}
+}
+
 func TestEndpoints_GetUserByEmail(t *testing.T) {
 	for _, test := range []struct {
 		name    string
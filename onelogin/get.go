@@ -13,12 +13,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/allcloud-io/clisso/aws"
 	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
 	"github.com/allcloud-io/clisso/keychain"
 	"github.com/allcloud-io/clisso/log"
-	"github.com/allcloud-io/clisso/saml"
 	"github.com/allcloud-io/clisso/spinner"
+	"github.com/allcloud-io/clisso/tokencache"
 	"github.com/allcloud-io/clisso/yubikey"
 	"github.com/icza/gog"
 	"github.com/spf13/viper"
@@ -41,9 +41,16 @@ const (
 )
 
 var (
-	keyChain = keychain.DefaultKeychain{}
+	keyChain keychain.Keychain = keychain.DefaultKeychain{}
 )
 
+func init() {
+	idp.Register("onelogin", Provider{})
+}
+
+// Provider implements idp.Provider for OneLogin.
+type Provider struct{}
+
 type DeviceOptions struct {
 	// Detect if a YubiKey is inserted and automatically select the device
 	IsYubiKeyAutoDetected bool
@@ -87,43 +94,38 @@ func (d *DeviceOptions) setMfaDevice() {
 	}
 }
 
-// Get gets temporary credentials for the given app.
-// TODO Move AWS logic outside this function.
-func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool) (*aws.Credentials, error) {
+// Assertion logs in to OneLogin and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
 	log.WithFields(log.Fields{
-		"app":         app,
-		"provider":    provider,
-		"pArn":        pArn,
-		"awsRegion":   awsRegion,
-		"duration":    duration,
-		"interactive": interactive,
-	}).Trace("Getting credentials from OneLogin")
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from OneLogin")
 	// Read config
-	p, err := config.GetOneLoginProvider(provider)
+	p, err := config.GetOneLoginProvider(r.Provider)
 	if err != nil {
-		return nil, fmt.Errorf("reading provider config: %v", err)
+		return "", fmt.Errorf("reading provider config: %v", err)
 	}
 
-	a, err := config.GetOneLoginApp(app)
+	a, err := config.GetOneLoginApp(r.App)
 	if err != nil {
-		return nil, fmt.Errorf("reading config for app %s: %v", app, err)
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
 	}
 
-	c, err := NewClient(p.Region)
+	c, err := NewClient(p.Region, p.APIBaseURL)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// Initialize spinner
-	var s = spinner.New(interactive)
+	var s = spinner.New(r.Interactive)
 
-	// Get OneLogin access token
+	// Get OneLogin access token, reusing the cached one if still valid
 	s.Start()
-	log.Trace("Generating access token")
-	token, err := c.GenerateTokens(p.ClientID, p.ClientSecret)
+	token, err := accessToken(c, r.Provider, p)
 	s.Stop()
 	if err != nil {
-		return nil, fmt.Errorf("generating access token: %s", err)
+		return "", fmt.Errorf("generating access token: %s", err)
 	}
 
 	user := p.Username
@@ -133,14 +135,14 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 		fmt.Print("OneLogin username: ")
 		_, err = fmt.Scanln(&user)
 		if err != nil {
-			return nil, fmt.Errorf("reading username: %v", err)
+			return "", fmt.Errorf("reading username: %v", err)
 		}
 
 	}
 
-	pass, err := keyChain.Get(provider)
+	pass, err := keyChain.Get(r.Provider)
 	if err != nil {
-		return nil, fmt.Errorf("error getting keychain: %s", err)
+		return "", fmt.Errorf("error getting keychain: %s", err)
 	}
 
 	// Generate SAML assertion
@@ -163,9 +165,21 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 
 	s.Start()
 	rSaml, err := c.GenerateSamlAssertion(token, &pSAML)
+	if errors.Is(err, errUnauthorized) {
+		// The cached access token has been rejected, e.g. because it was revoked. Drop it and try
+		// once more with a new one.
+		log.Debug("Cached OneLogin access token rejected, generating a new one")
+		if err = tokencache.Delete(tokenCacheKey(c, r.Provider, p.ClientID)); err != nil {
+			log.WithError(err).Warn("Couldn't remove cached OneLogin access token")
+		}
+		token, err = accessToken(c, r.Provider, p)
+		if err == nil {
+			rSaml, err = c.GenerateSamlAssertion(token, &pSAML)
+		}
+	}
 	s.Stop()
 	if err != nil {
-		return nil, fmt.Errorf("generating SAML assertion: %v", err)
+		return "", fmt.Errorf("generating SAML assertion: %v", err)
 	}
 
 	log.WithField("Message", rSaml.Message).Debug("GenerateSamlAssertion is done")
@@ -181,7 +195,7 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 
 		device, err := getDevice(devices, deviceOpts)
 		if err != nil {
-			return nil, fmt.Errorf("error getting devices: %s", err)
+			return "", fmt.Errorf("error getting devices: %s", err)
 		}
 
 		var rMfa *VerifyFactorResponse
@@ -208,11 +222,11 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 			rMfa, err = c.VerifyFactor(token, &pMfa)
 			s.Stop()
 			if err != nil {
-				return nil, err
+				return "", err
 			}
 
 			pMfa.DoNotNotify = true
-			if interactive {
+			if r.Interactive {
 				fmt.Println(rMfa.Message)
 			} else {
 				// print to StdErr if we're not interactive
@@ -227,7 +241,7 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 				rMfa, err = c.VerifyFactor(token, &pMfa)
 				if err != nil {
 					s.Stop()
-					return nil, err
+					return "", err
 				}
 
 				timeout -= MFAInterval
@@ -246,7 +260,7 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 			var otp string
 			_, err = fmt.Scanln(&otp)
 			if err != nil {
-				return nil, fmt.Errorf("reading OTP: %v", err)
+				return "", fmt.Errorf("reading OTP: %v", err)
 			}
 
 			// Verify MFA
@@ -262,7 +276,7 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 			rMfa, err = c.VerifyFactor(token, &pMfa)
 			s.Stop()
 			if err != nil {
-				return nil, fmt.Errorf("verifying factor: %v", err)
+				return "", fmt.Errorf("verifying factor: %v", err)
 			}
 		}
 		rData = rMfa.Data
@@ -271,28 +285,7 @@ func Get(app, provider, pArn, awsRegion string, duration int32, interactive bool
 		rData = rSaml.Data
 	}
 
-	arn, err := saml.Get(rData, pArn)
-	if err != nil {
-		return nil, err
-	}
-
-	s.Start()
-	creds, err := aws.AssumeSAMLRole(arn.Provider, arn.Role, rData, awsRegion, duration)
-	s.Stop()
-
-	if err != nil {
-		if err.Error() == aws.ErrDurationExceeded {
-			log.Warn(aws.DurationExceededMessage)
-			s.Start()
-			creds, err = aws.AssumeSAMLRole(arn.Provider, arn.Role, rData, awsRegion, 3600)
-			s.Stop()
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	return creds, err
+	return rData, nil
 }
 
 // getDevice gets a slice of MFA devices, prompts the user to select one and returns the selected device.
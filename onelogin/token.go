@@ -0,0 +1,123 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package onelogin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/tokencache"
+)
+
+// refreshMargin is the time before the access token expires when it is refreshed, so that it
+// doesn't expire while clisso is using it.
+const refreshMargin = 5 * time.Minute
+
+// cachedToken is an API access token cached across invocations of clisso.
+type cachedToken struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// tokenCacheKey returns the cache key of the access token for the given provider. The client ID
+// and the API base URL are part of the key, so that changed API credentials don't use a stale
+// token and a token is never sent to a different API.
+func tokenCacheKey(c *Client, provider, clientID string) string {
+	var base string
+	if c.Endpoints.base != nil {
+		base = c.Endpoints.base.String()
+	}
+	return fmt.Sprintf("onelogin-token:%s:%s:%s", provider, clientID, base)
+}
+
+// accessToken returns an API access token for the provider. A cached token is used until shortly
+// before it expires, and then refreshed using its refresh token. New tokens are generated using
+// the API credentials if there is no cached token or refreshing fails.
+func accessToken(c *Client, provider string, p *config.OneLoginProviderConfig) (string, error) {
+	key := tokenCacheKey(c, provider, p.ClientID)
+
+	var ct cachedToken
+	ok, err := tokencache.Load(key, &ct)
+	if err != nil {
+		log.WithError(err).Warn("Couldn't read cached OneLogin access token")
+	}
+	if ok && ct.AccessToken != "" && time.Now().Add(refreshMargin).Before(ct.ExpiresAt) {
+		log.Debug("Using cached OneLogin access token")
+		return ct.AccessToken, nil
+	}
+
+	var resp *GenerateTokensResponse
+	if ok && ct.RefreshToken != "" {
+		log.Debug("Refreshing OneLogin access token")
+		resp, err = c.RefreshTokens(p.ClientID, p.ClientSecret, ct.AccessToken, ct.RefreshToken)
+		if err != nil {
+			log.WithError(err).Debug("Couldn't refresh OneLogin access token, generating a new one")
+		}
+	}
+	if resp == nil {
+		log.Trace("Generating access token")
+		resp, err = c.GenerateTokens(p.ClientID, p.ClientSecret)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	ct = cachedToken{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+	if err = tokencache.Save(key, &ct); err != nil {
+		log.WithError(err).Warn("Couldn't cache OneLogin access token")
+	}
+
+	return ct.AccessToken, nil
+}
+
+// RevokeToken revokes the cached API access token of the provider, if there is one, and removes
+// it from the cache. The token is removed even if revoking it fails.
+func RevokeToken(provider string) error {
+	p, err := config.GetOneLoginProvider(provider)
+	if err != nil {
+		return fmt.Errorf("reading provider config: %v", err)
+	}
+
+	c, err := NewClient(p.Region, p.APIBaseURL)
+	if err != nil {
+		return err
+	}
+
+	return revokeToken(c, provider, p)
+}
+
+func revokeToken(c *Client, provider string, p *config.OneLoginProviderConfig) error {
+	key := tokenCacheKey(c, provider, p.ClientID)
+
+	var ct cachedToken
+	ok, err := tokencache.Load(key, &ct)
+	if err != nil {
+		return fmt.Errorf("reading cached access token: %v", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	// An expired token can't be revoked, but is removed from the cache nonetheless
+	if time.Now().Before(ct.ExpiresAt) {
+		err = c.RevokeToken(p.ClientID, p.ClientSecret, ct.AccessToken)
+	}
+	if delErr := tokencache.Delete(key); delErr != nil {
+		return delErr
+	}
+	if err != nil {
+		return fmt.Errorf("revoking access token: %v", err)
+	}
+
+	return nil
+}
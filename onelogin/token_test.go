@@ -0,0 +1,196 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package onelogin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/tokencache"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenCalls counts the calls to the token endpoints of the test server.
+type tokenCalls struct {
+	Generated, Refreshed, Revoked int
+}
+
+// getTokenTestServer returns a stand-in for the OneLogin token endpoints. Tokens expire after the
+// given number of seconds. Refreshing fails unless the refresh token is valid_refresh_token.
+func getTokenTestServer(t *testing.T, calls *tokenCalls, expiresIn int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(GenerateTokensPath, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "client_id:fake_id, client_secret:fake_secret", r.Header.Get("Authorization"))
+		var p GenerateTokensParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+
+		switch p.GrantType {
+		case "client_credentials":
+			calls.Generated++
+		case "refresh_token":
+			if p.RefreshToken != "valid_refresh_token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			calls.Refreshed++
+		}
+		fmt.Fprintf(w, `{"access_token":"token_%d_%d","refresh_token":"valid_refresh_token","expires_in":%d}`,
+			calls.Generated, calls.Refreshed, expiresIn)
+	})
+	mux.HandleFunc(GenerateSamlAssertionPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer:token_1_0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"message":"Success","data":"fake_assertion"}`)
+	})
+	mux.HandleFunc(RevokeTokenPath, func(w http.ResponseWriter, r *http.Request) {
+		var p RevokeTokenParams
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&p))
+		assert.Equal(t, "token_1_0", p.AccessToken)
+		calls.Revoked++
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestAccessToken(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	var calls tokenCalls
+	ts := getTokenTestServer(t, &calls, 36000)
+	defer ts.Close()
+
+	c, err := NewClient("", ts.URL)
+	assert.Nil(t, err)
+	p := &config.OneLoginProviderConfig{ClientID: "fake_id", ClientSecret: "fake_secret"}
+
+	// A new token is generated and cached
+	token, err := accessToken(c, "onelogin", p)
+	assert.Nil(t, err)
+	assert.Equal(t, "token_1_0", token)
+
+	// The cached token is reused
+	token, err = accessToken(c, "onelogin", p)
+	assert.Nil(t, err)
+	assert.Equal(t, "token_1_0", token)
+	assert.Equal(t, tokenCalls{Generated: 1}, calls)
+
+	// The token is revoked and removed from the cache
+	assert.Nil(t, revokeToken(c, "onelogin", p))
+	assert.Equal(t, 1, calls.Revoked)
+	ok, err := tokencache.Load(tokenCacheKey(c, "onelogin", p.ClientID), &cachedToken{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// Nothing to revoke
+	assert.Nil(t, revokeToken(c, "onelogin", p))
+	assert.Equal(t, 1, calls.Revoked)
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	var calls tokenCalls
+	ts := getTokenTestServer(t, &calls, 36000)
+	defer ts.Close()
+
+	c, err := NewClient("", ts.URL)
+	assert.Nil(t, err)
+	p := &config.OneLoginProviderConfig{ClientID: "fake_id", ClientSecret: "fake_secret"}
+	key := tokenCacheKey(c, "onelogin", p.ClientID)
+
+	// A token about to expire is refreshed
+	expiresAt := time.Now().Add(time.Minute)
+	assert.Nil(t, tokencache.Save(key, &cachedToken{AccessToken: "old", RefreshToken: "valid_refresh_token", ExpiresAt: expiresAt}))
+	token, err := accessToken(c, "onelogin", p)
+	assert.Nil(t, err)
+	assert.Equal(t, "token_0_1", token)
+	assert.Equal(t, tokenCalls{Refreshed: 1}, calls)
+
+	var ct cachedToken
+	_, err = tokencache.Load(key, &ct)
+	assert.Nil(t, err)
+	assert.Equal(t, "token_0_1", ct.AccessToken)
+	assert.True(t, ct.ExpiresAt.After(time.Now().Add(time.Hour)))
+
+	// A new token is generated if refreshing fails
+	assert.Nil(t, tokencache.Save(key, &cachedToken{AccessToken: "old", RefreshToken: "revoked", ExpiresAt: expiresAt}))
+	token, err = accessToken(c, "onelogin", p)
+	assert.Nil(t, err)
+	assert.Equal(t, "token_1_1", token)
+	assert.Equal(t, tokenCalls{Generated: 1, Refreshed: 1}, calls)
+}
+
+func TestTokenCacheKey(t *testing.T) {
+	us, err := NewClient("US", "")
+	assert.Nil(t, err)
+	eu, err := NewClient("EU", "")
+	assert.Nil(t, err)
+	custom, err := NewClient("US", "https://onelogin.example.com")
+	assert.Nil(t, err)
+
+	// Tokens aren't shared between APIs
+	assert.NotEqual(t, tokenCacheKey(us, "onelogin", "id"), tokenCacheKey(eu, "onelogin", "id"))
+	assert.NotEqual(t, tokenCacheKey(us, "onelogin", "id"), tokenCacheKey(custom, "onelogin", "id"))
+	assert.NotEqual(t, tokenCacheKey(us, "onelogin", "id"), tokenCacheKey(us, "onelogin", "other"))
+}
+
+// testKeychain is a stand-in for the key chain of the user.
+type testKeychain map[string][]byte
+
+func (k testKeychain) Get(provider string) ([]byte, error) { return k[provider], nil }
+func (k testKeychain) Set(provider string, password []byte) error {
+	k[provider] = password
+	return nil
+}
+
+func TestAssertionRejectedToken(t *testing.T) {
+	testutil.TempTokenCache(t)
+
+	defer func(k keychain.Keychain) { keyChain = k }(keyChain)
+	keyChain = testKeychain{"test-onelogin": []byte("secret")}
+
+	var calls tokenCalls
+	ts := getTokenTestServer(t, &calls, 36000)
+	defer ts.Close()
+
+	viper.Set("providers.test-onelogin", map[string]interface{}{
+		"type":          "onelogin",
+		"client-id":     "fake_id",
+		"client-secret": "fake_secret",
+		"subdomain":     "example",
+		"username":      "user",
+		"api-base-url":  ts.URL,
+	})
+	viper.Set("apps.test-onelogin-app", map[string]string{"provider": "test-onelogin", "app-id": "1"})
+
+	// The cached token has been revoked, but hasn't expired yet.
+	c, err := NewClient("", ts.URL)
+	assert.Nil(t, err)
+	key := tokenCacheKey(c, "test-onelogin", "fake_id")
+	assert.Nil(t, tokencache.Save(key, &cachedToken{AccessToken: "revoked", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	// The assertion is generated with a new token.
+	assertion, err := Provider{}.Assertion(&idp.Request{App: "test-onelogin-app", Provider: "test-onelogin"})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+	assert.Equal(t, tokenCalls{Generated: 1}, calls)
+
+	var ct cachedToken
+	_, err = tokencache.Load(key, &ct)
+	assert.Nil(t, err)
+	assert.Equal(t, "token_1_0", ct.AccessToken)
+}
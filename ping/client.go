@@ -0,0 +1,71 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package ping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/allcloud-io/clisso/htmlform"
+)
+
+// Client represents a PingFederate login client.
+type Client struct {
+	*htmlform.Client
+}
+
+// InitSSO starts an IdP-initiated SSO to the given SP connection and returns the first page of the
+// login flow.
+func (c *Client) InitSSO(partnerSpID string) (*htmlform.Page, error) {
+	u := fmt.Sprintf("%s/idp/startSSO.ping?%s", c.BaseURL, url.Values{"PartnerSpId": {partnerSpID}}.Encode())
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	return c.Load(req)
+}
+
+// PollStatus returns the status of a pending PingID push authentication. statusURL is the status
+// endpoint next to the action of the PingID polling form.
+func (c *Client) PollStatus(statusURL string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, statusURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("constructing HTTP request: %v", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	var s struct {
+		Status string `json:"status"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return "", fmt.Errorf("parsing status response: %v", err)
+	}
+
+	return s.Status, nil
+}
+
+// NewClient creates a new Client and returns a pointer to it.
+func NewClient(baseURL string) (*Client, error) {
+	c, err := htmlform.NewClient(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{Client: c}, nil
+}
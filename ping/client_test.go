@@ -0,0 +1,215 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package ping
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allcloud-io/clisso/internal/testutil"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+const loginPage = `<html><body>
+<form method="POST" action="/idp/abc12/resumeSAML20/idp/startSSO.ping" autocomplete="off">
+	%s
+	<input type="text" id="username" name="pf.username" value="">
+	<input type="password" id="password" name="pf.pass" value="">
+	<input type="hidden" name="pf.ok" value="">
+	<input type="hidden" name="pf.cancel" value="">
+	<a href="#" onclick="postOk();" class="ping-button normal allow" title="Sign On">Sign On</a>
+</form></body></html>`
+
+const ppmPage = `<html><body onload="document.forms[0].submit()">
+<noscript><p>Note: Since your browser does not support JavaScript, you must press the Resume button once to proceed.</p></noscript>
+<form method="post" action="/pingid/ppm/auth">
+	<input type="hidden" name="ppm_request" value="ppm_token"/>
+	<input type="hidden" name="idp_account_id" value="account"/>
+	<noscript><input type="submit" value="Resume"/></noscript>
+</form></body></html>`
+
+const pushPage = `<html><body>
+<form id="form1" method="post" action="/pingid/ppm/auth/poll">
+	<input type="hidden" name="csrfToken" value="csrf"/>
+	<input type="hidden" name="isSwipe" value="true"/>
+</form>
+<div class="swipe-text">Authenticating on your device</div>
+</body></html>`
+
+const otpPage = `<html><body>
+%s
+<form id="otp-form" method="post" action="/pingid/ppm/auth/otp">
+	<input type="hidden" name="csrfToken" value="csrf"/>
+	<input type="text" name="otp" id="otp" autocomplete="off"/>
+	<input type="submit" value="Sign On"/>
+</form></body></html>`
+
+const resumePage = `<html><body onload="document.forms[0].submit()">
+<form method="post" action="/idp/abc12/resumeSAML20/idp/startSSO.ping">
+	<input type="hidden" name="ppm_response" value="ppm_response"/>
+</form></body></html>`
+
+const samlPage = `<html><body onload="javascript:document.forms[0].submit()">
+<form method="post" action="https://signin.aws.amazon.com/saml">
+	<input type="hidden" name="SAMLResponse" value="fake_assertion"/>
+	<noscript><input type="submit" value="Resume"/></noscript>
+</form></body></html>`
+
+// getTestServer returns a stand-in for PingFederate and PingID. The PingID challenge is a push
+// unless otp is set.
+func getTestServer(t *testing.T, otp bool) *httptest.Server {
+	var polls int
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/idp/startSSO.ping", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "urn:amazon:webservices", r.URL.Query().Get("PartnerSpId"))
+		http.SetCookie(w, &http.Cookie{Name: "PF", Value: "session", Path: "/"})
+		fmt.Fprintf(w, loginPage, "")
+	})
+	mux.HandleFunc("/idp/abc12/resumeSAML20/idp/startSSO.ping", func(w http.ResponseWriter, r *http.Request) {
+		_, err := r.Cookie("PF")
+		assert.Nil(t, err)
+		if r.PostFormValue("ppm_response") != "" {
+			fmt.Fprint(w, samlPage)
+			return
+		}
+		assert.Equal(t, "clicked", r.PostFormValue("pf.ok"))
+		if r.PostFormValue("pf.username") != "user" || r.PostFormValue("pf.pass") != "secret" {
+			fmt.Fprintf(w, loginPage, `<div class="ping-error">We didn't recognize the username or password you entered. Please try again.</div>`)
+			return
+		}
+		fmt.Fprint(w, ppmPage)
+	})
+	mux.HandleFunc("/pingid/ppm/auth", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ppm_token", r.PostFormValue("ppm_request"))
+		if otp {
+			fmt.Fprintf(w, otpPage, "")
+			return
+		}
+		fmt.Fprint(w, pushPage)
+	})
+	mux.HandleFunc("/pingid/ppm/auth/status", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			fmt.Fprint(w, `{"status":"in_progress"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"approved"}`)
+	})
+	mux.HandleFunc("/pingid/ppm/auth/poll", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, polls)
+		assert.Equal(t, "csrf", r.PostFormValue("csrfToken"))
+		fmt.Fprint(w, resumePage)
+	})
+	mux.HandleFunc("/pingid/ppm/auth/otp", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("otp") != "123456" {
+			fmt.Fprintf(w, otpPage, `<div class="error-message">Invalid passcode.</div>`)
+			return
+		}
+		fmt.Fprint(w, resumePage)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestLoginPush(t *testing.T) {
+	mfaInterval = time.Millisecond
+
+	ts := getTestServer(t, false)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	assertion, err := login(c, &loginParams{PartnerSpID: DefaultPartnerSpID, Username: "user", Password: "secret"})
+	assert.Nil(t, err)
+	assert.Equal(t, "fake_assertion", assertion)
+}
+
+func TestLoginOTP(t *testing.T) {
+	ts := getTestServer(t, true)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	testutil.WithStdin(t, "123456\n", func() {
+		stdout, stderr := testutil.CaptureOutput(t, func() {
+			assertion, err := login(c, &loginParams{PartnerSpID: DefaultPartnerSpID, Username: "user", Password: "secret"})
+			assert.Nil(t, err)
+			assert.Equal(t, "fake_assertion", assertion)
+		})
+		// The passcode prompt goes to stderr when not interactive, since stdout may carry
+		// credentials.
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "Please enter the PingID passcode")
+	})
+}
+
+func TestLoginWrongOTP(t *testing.T) {
+	ts := getTestServer(t, true)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	testutil.WithStdin(t, "000000\n", func() {
+		_, err := login(c, &loginParams{PartnerSpID: DefaultPartnerSpID, Username: "user", Password: "secret"})
+		assert.EqualError(t, err, "OTP verification failed: Invalid passcode.")
+	})
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	ts := getTestServer(t, false)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	assert.Nil(t, err)
+
+	_, err = login(c, &loginParams{PartnerSpID: DefaultPartnerSpID, Username: "user", Password: "wrong"})
+	assert.EqualError(t, err, "login failed: We didn't recognize the username or password you entered. Please try again.")
+}
+
+func TestWaitForPush(t *testing.T) {
+	mfaInterval = time.Millisecond
+
+	cases := []struct {
+		Status        string
+		ExpectedError string
+	}{
+		{Status: "approved"},
+		{Status: "rejected", ExpectedError: "PingID push was rejected"},
+		{Status: "timeout", ExpectedError: "PingID push timed out"},
+		{Status: "error", ExpectedError: "unexpected PingID push status 'error'"},
+		{Status: "", ExpectedError: "unexpected PingID push status ''"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Status, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/pingid/ppm/auth/status", r.URL.Path)
+				fmt.Fprintf(w, `{"status":"%s"}`, tc.Status)
+			}))
+			defer ts.Close()
+
+			c, err := NewClient(ts.URL)
+			assert.Nil(t, err)
+
+			err = waitForPush(c, ts.URL+"/pingid/ppm/auth/poll", false)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
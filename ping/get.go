@@ -0,0 +1,279 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/allcloud-io/clisso/config"
+	"github.com/allcloud-io/clisso/htmlform"
+	"github.com/allcloud-io/clisso/idp"
+	"github.com/allcloud-io/clisso/internal/prompt"
+	"github.com/allcloud-io/clisso/keychain"
+	"github.com/allcloud-io/clisso/log"
+	"github.com/allcloud-io/clisso/spinner"
+	"github.com/icza/gog"
+)
+
+const (
+	// DefaultPartnerSpID is the entity ID of the AWS SP connection.
+	DefaultPartnerSpID = "urn:amazon:webservices"
+
+	// MFAPushTimeout is the time to wait for a PingID push to be approved.
+	MFAPushTimeout = 60 * time.Second
+
+	// Selectors of the forms making up the PingFederate and PingID login flows.
+	loginFormSelector = `form:has(input[name="pf.username"]), form:has(input[name="pf.pass"])`
+	otpFormSelector   = "form#otp-form"
+	pushFormSelector  = `form#form1[action*="/pingid/ppm/auth/poll"]`
+
+	// visibleInputSelector matches the inputs a user would fill in. Forms without any are
+	// intermediate pages which are normally submitted by JavaScript.
+	visibleInputSelector = `input:not([type="hidden"]):not([type="submit"]):not([type="button"])`
+
+	// maxPages limits the number of pages followed during a single login.
+	maxPages = 15
+)
+
+var (
+	keyChain = keychain.DefaultKeychain{}
+
+	// mfaInterval is the time to wait between polling the status of a PingID push.
+	mfaInterval = 2 * time.Second
+)
+
+func init() {
+	idp.Register("ping", Provider{})
+}
+
+// Provider implements idp.Provider for PingFederate.
+type Provider struct{}
+
+// Assertion logs in to PingFederate and returns a SAML assertion for the requested app.
+func (Provider) Assertion(r *idp.Request) (string, error) {
+	log.WithFields(log.Fields{
+		"app":         r.App,
+		"provider":    r.Provider,
+		"interactive": r.Interactive,
+	}).Trace("Getting SAML assertion from PingFederate")
+
+	p, err := config.GetPingProvider(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("reading provider config: %v", err)
+	}
+
+	a, err := config.GetPingApp(r.App)
+	if err != nil {
+		return "", fmt.Errorf("reading config for app %s: %v", r.App, err)
+	}
+
+	c, err := NewClient(p.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("initializing PingFederate client: %v", err)
+	}
+
+	user := p.Username
+	if user == "" {
+		user, err = prompt.Ask("PingFederate username: ", r.Interactive)
+		if err != nil {
+			return "", fmt.Errorf("reading username: %v", err)
+		}
+	}
+
+	pass, err := keyChain.Get(r.Provider)
+	if err != nil {
+		return "", fmt.Errorf("getting key chain: %v", err)
+	}
+
+	partnerSpID := a.PartnerSpID
+	if partnerSpID == "" {
+		partnerSpID = DefaultPartnerSpID
+	}
+
+	log.WithFields(log.Fields{
+		"Username": user,
+		// print password only in Trace Log Level
+		"Password":    gog.If(log.GetLevel() == log.TraceLevel, string(pass), "<redacted>"),
+		"PartnerSpId": partnerSpID,
+	}).Debug("Logging in to PingFederate")
+
+	return login(c, &loginParams{
+		PartnerSpID: partnerSpID,
+		Username:    user,
+		Password:    string(pass),
+		Interactive: r.Interactive,
+	})
+}
+
+type loginParams struct {
+	PartnerSpID string
+	Username    string
+	Password    string
+	Interactive bool
+}
+
+// login walks through the pages of the PingFederate login flow, including the PingID pages, until
+// a page carrying the SAML response is returned.
+func login(c *Client, p *loginParams) (string, error) {
+	s := spinner.New(p.Interactive)
+
+	s.Start()
+	page, err := c.InitSSO(p.PartnerSpID)
+	s.Stop()
+	if err != nil {
+		return "", fmt.Errorf("starting login: %v", err)
+	}
+
+	var usernameSent, passwordSent, otpSent bool
+	for i := 0; i < maxPages; i++ {
+		if assertion := page.SAMLResponse(); assertion != "" {
+			return assertion, nil
+		}
+
+		switch {
+		case page.Doc.Find(loginFormSelector).Length() > 0:
+			form, err := page.Form(loginFormSelector)
+			if err != nil {
+				return "", err
+			}
+			_, hasPassword := form.Values["pf.pass"]
+			if (hasPassword && passwordSent) || (!hasPassword && usernameSent) {
+				return "", fmt.Errorf("login failed: %s", pageError(page))
+			}
+			usernameSent = true
+			passwordSent = passwordSent || hasPassword
+
+			form.Values.Set("pf.username", p.Username)
+			if hasPassword {
+				form.Values.Set("pf.pass", p.Password)
+			}
+			form.Values.Set("pf.ok", "clicked")
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("submitting login form: %v", err)
+			}
+		case page.Doc.Find(otpFormSelector).Length() > 0:
+			if otpSent {
+				return "", fmt.Errorf("OTP verification failed: %s", pageError(page))
+			}
+			otpSent = true
+
+			form, err := page.Form(otpFormSelector)
+			if err != nil {
+				return "", err
+			}
+
+			otp, err := prompt.Ask("Please enter the PingID passcode: ", p.Interactive)
+			if err != nil {
+				return "", fmt.Errorf("reading OTP: %v", err)
+			}
+			form.Values.Set("otp", otp)
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("verifying OTP: %v", err)
+			}
+		case page.Doc.Find(pushFormSelector).Length() > 0:
+			form, err := page.Form(pushFormSelector)
+			if err != nil {
+				return "", err
+			}
+			if err = waitForPush(c, form.Action, p.Interactive); err != nil {
+				return "", err
+			}
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("completing PingID push: %v", err)
+			}
+		case isAutoSubmit(page.Doc):
+			form, err := page.Form("form")
+			if err != nil {
+				return "", err
+			}
+			log.WithField("action", form.Action).Trace("Submitting intermediate form")
+
+			s.Start()
+			page, err = c.Submit(form)
+			s.Stop()
+			if err != nil {
+				return "", fmt.Errorf("submitting form: %v", err)
+			}
+		default:
+			return "", fmt.Errorf("unexpected login page: %s", pageError(page))
+		}
+	}
+
+	return "", errors.New("no SAML response received from PingFederate")
+}
+
+// waitForPush polls the status of a PingID push until it is no longer pending. Only an approved
+// push is successful, any other status is an error.
+func waitForPush(c *Client, pollAction string, interactive bool) error {
+	u, err := url.Parse(pollAction)
+	if err != nil {
+		return fmt.Errorf("parsing PingID polling URL: %v", err)
+	}
+	statusURL := u.ResolveReference(&url.URL{Path: "status"}).String()
+
+	s := spinner.New(interactive)
+	msg := "Please approve the PingID notification on your device"
+	fmt.Fprintln(prompt.Output(interactive), msg)
+	s.Start()
+	defer s.Stop()
+
+	timeout := time.Now().Add(MFAPushTimeout)
+	for time.Now().Before(timeout) {
+		status, err := c.PollStatus(statusURL)
+		if err != nil {
+			return fmt.Errorf("polling PingID push status: %v", err)
+		}
+		log.WithField("status", status).Trace("PingID push status")
+
+		switch status {
+		case "in_progress":
+			time.Sleep(mfaInterval)
+		case "timeout":
+			return errors.New("PingID push timed out")
+		case "rejected", "declined":
+			return errors.New("PingID push was rejected")
+		case "approved", "success":
+			return nil
+		default:
+			return fmt.Errorf("unexpected PingID push status '%s'", status)
+		}
+	}
+
+	return errors.New("timeout waiting for PingID push approval")
+}
+
+// isAutoSubmit reports whether the page consists of a single form without any input for the user,
+// such as the forms PingFederate and PingID submit using JavaScript.
+func isAutoSubmit(doc *goquery.Document) bool {
+	forms := doc.Find("form")
+	return forms.Length() == 1 && forms.Find(visibleInputSelector).Length() == 0
+}
+
+// pageError returns the error message shown on a PingFederate or PingID page.
+func pageError(page *htmlform.Page) string {
+	msg := strings.TrimSpace(page.Doc.Find(".ping-error, .error-message, #error-message, .validation-error").First().Text())
+	if msg == "" {
+		return "unknown error"
+	}
+	return msg
+}
@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/allcloud-io/clisso/internal/prompt"
 	"github.com/allcloud-io/clisso/log"
 	"github.com/crewjam/saml"
 	"github.com/spf13/viper"
@@ -177,9 +178,8 @@ func ask(arns []ARN) (idx int) {
 			fmt.Printf("%d. %s\n", i+1, name)
 		}
 
-		var input string
 		fmt.Print("Please select an IAM role to assume: ")
-		_, err := fmt.Scanln(&input)
+		input, err := prompt.ReadLine()
 		if err != nil {
 			fmt.Printf("Error reading input: %v\n", err)
 			continue
@@ -0,0 +1,109 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+
+// Package tokencache stores tokens obtained from identity providers on disk, so they can be
+// reused across invocations of clisso. Entries are JSON files only readable by the current user.
+package tokencache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/allcloud-io/clisso/log"
+)
+
+// Dir returns the directory cache entries are stored in. It can be replaced in tests.
+var Dir = func() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "clisso"), nil
+}
+
+// path returns the path of the file for the given key. Keys are hashed since they may contain
+// characters which aren't allowed in file names, such as URLs.
+func path(key string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", fmt.Errorf("getting cache directory: %v", err)
+	}
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// Load reads the entry stored under key into v. It returns false if there is no such entry.
+func Load(key string, v interface{}) (bool, error) {
+	p, err := path(key)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading cache entry: %v", err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("parsing cache entry: %v", err)
+	}
+	log.WithField("key", key).Trace("Cache entry loaded")
+
+	return true, nil
+}
+
+// Save stores v under key, replacing any existing entry.
+func Save(key string, v interface{}) error {
+	p, err := path(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("serializing cache entry: %v", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return fmt.Errorf("creating cache directory: %v", err)
+	}
+	// Write to a temporary file first so a concurrent Load never reads a partial entry.
+	tmp := p + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing cache entry: %v", err)
+	}
+	if err = os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("writing cache entry: %v", err)
+	}
+	log.WithField("key", key).Trace("Cache entry saved")
+
+	return nil
+}
+
+// Delete removes the entry stored under key. Deleting an entry which doesn't exist isn't an
+// error.
+func Delete(key string) error {
+	p, err := path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("deleting cache entry: %v", err)
+	}
+	log.WithField("key", key).Trace("Cache entry deleted")
+
+	return nil
+}
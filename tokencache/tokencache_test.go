@@ -0,0 +1,54 @@
+/*
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ */
+package tokencache
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/allcloud-io/clisso/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var _, _ = log.SetupLogger("panic", "", false, true)
+
+type entry struct {
+	Token string `json:"token"`
+}
+
+func TestCycle(t *testing.T) {
+	dir := t.TempDir()
+	Dir = func() (string, error) { return dir + "/clisso", nil }
+
+	var e entry
+	ok, err := Load("https://example.com/start", &e)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	err = Save("https://example.com/start", entry{Token: "abc"})
+	assert.Nil(t, err)
+
+	ok, err = Load("https://example.com/start", &e)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", e.Token)
+
+	if runtime.GOOS != "windows" {
+		p, err := path("https://example.com/start")
+		assert.Nil(t, err)
+		fi, err := os.Stat(p)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	assert.Nil(t, Delete("https://example.com/start"))
+	assert.Nil(t, Delete("https://example.com/start"))
+
+	ok, err = Load("https://example.com/start", &e)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}